@@ -3,6 +3,7 @@ package config
 import (
 	"io"
 	"io/ioutil"
+	"path"
 
 	"github.com/BurntSushi/toml"
 	"github.com/juju/errors"
@@ -11,15 +12,31 @@ import (
 
 // Config is cherry picker config struct
 type Config struct {
-	GithubToken     string    `toml:"github-token"`
-	SlackToken      string    `toml:"slack-token"`
-	SlackChannel    string    `toml:"slack-channel"`
-	Repos           []string  `toml:"repos"`
-	ReleaseNoteRepo string    `toml:"release-note-repo"`
-	ReleaseNotePath string    `toml:"release-note-path"`
-	PullLanguage    string    `toml:"pull-language"`
-	GitDir          string    `toml:"git-dir"`
-	Products        []Product `toml:"product"`
+	GithubToken     string   `toml:"github-token"`
+	SlackToken      string   `toml:"slack-token"`
+	SlackChannel    string   `toml:"slack-channel"`
+	Repos           []string `toml:"repos"`
+	ReleaseNoteRepo string   `toml:"release-note-repo"`
+	ReleaseNotePath string   `toml:"release-note-path"`
+	PullLanguage    string   `toml:"pull-language"`
+	Languages       []string `toml:"languages"`
+	// CollapseNotes joins bullets of release note in a pull request into one note
+	CollapseNotes bool      `toml:"collapse-notes"`
+	GitDir        string    `toml:"git-dir"`
+	CacheDir      string    `toml:"cache-dir"`
+	GraphQL       bool      `toml:"graphql"`
+	Concurrency   int       `toml:"concurrency"`
+	Products      []Product `toml:"product"`
+	Forges        []Forge   `toml:"forge"`
+	// Extractions are rules to extract release notes from pull requests, the one without repos is the default
+	Extractions []Extraction `toml:"extraction"`
+	// Milestones are title patterns of milestones of repos, the one without repos is the default
+	Milestones []Milestone `toml:"milestone"`
+	// Branches are templates of release branches of repos, the one without repos is the default
+	Branches []Branch `toml:"branch"`
+	Lint     Lint     `toml:"lint"`
+	// CherryPickLabels are globs of labels added by the cherry-pick bot
+	CherryPickLabels []string `toml:"cherry-pick-labels"`
 }
 
 // Product can contain multi repos
@@ -29,6 +46,74 @@ type Product struct {
 	Rename     map[string]string `toml:"rename"`
 	Structure  []string          `toml:"structure"`
 	Label2Type map[string]string `toml:"label2type"`
+	// LabelRules map labels to types in precedence order before label2type, labels can be globs or regular expressions
+	LabelRules []LabelRule `toml:"label-rule"`
+	// MultiType puts a pull in the types of all matched labels, instead of the first one
+	MultiType bool `toml:"multi-type"`
+	// TitleTypes maps conventional commit types in pull titles to types, used if no label matches
+	TitleTypes map[string]string `toml:"title-types"`
+	// TypeOrder is the order of type sections, see parser.ClassOrder
+	TypeOrder []string `toml:"type-order"`
+	// NoteOrder sorts notes in a section by number, merged-at or author, number by default
+	NoteOrder string `toml:"note-order"`
+	// Template is the path of release note template file, built-in template is used if empty
+	Template string `toml:"template"`
+}
+
+// LabelRule maps labels which match Label to Type,
+// Label is a glob like "type/*", or a regular expression in slashes like "/^type\/(bug|fix)$/"
+type LabelRule struct {
+	Label string `toml:"label"`
+	Type  string `toml:"type"`
+}
+
+// Forge is a code hosting service which serves some repos,
+// repos not listed in any forge are served by github.com
+type Forge struct {
+	Name   string   `toml:"name"`
+	Type   string   `toml:"type"`
+	APIURL string   `toml:"api-url"`
+	GitURL string   `toml:"git-url"`
+	Token  string   `toml:"token"`
+	Repos  []string `toml:"repos"`
+}
+
+// Extraction is the rule to extract release notes from pull requests of repos,
+// empty fields use the rule for TiDB's pull request template
+type Extraction struct {
+	Repos []string `toml:"repos"`
+	// Headings maps languages to case insensitive patterns of release note headings
+	Headings map[string]string `toml:"headings"`
+	// EndHeading is the pattern of headings which end release notes
+	EndHeading string `toml:"end-heading"`
+	// NAPhrases mean there is no release note, they are case insensitive
+	NAPhrases    []string `toml:"na-phrases"`
+	NoNoteLabels []string `toml:"no-note-labels"`
+	// CodeBlock reads release notes from "```release-note" blocks first
+	CodeBlock bool `toml:"code-block"`
+}
+
+// Milestone is how milestone titles of repos match versions,
+// {version} in titles is the version with an optional leading "v", titles are case insensitive
+type Milestone struct {
+	Repos  []string `toml:"repos"`
+	Titles []string `toml:"titles"`
+}
+
+// Branch is how versions map to release branches of repos,
+// placeholders {major}, {minor}, {patch}, {pre-release} and {version} are filled by the version
+type Branch struct {
+	Repos []string `toml:"repos"`
+	// Templates are tried in order, the first existing branch is used
+	Templates []string `toml:"templates"`
+}
+
+// Lint is the rules of lint-notes, zero values use the default rules
+type Lint struct {
+	// RequireNoteLabels are labels of pulls which must have release notes
+	RequireNoteLabels []string `toml:"require-note-labels"`
+	MinLength         int      `toml:"min-length"`
+	MaxLength         int      `toml:"max-length"`
 }
 
 // New inits config by default
@@ -36,6 +121,8 @@ func New() *Config {
 	return &Config{
 		PullLanguage: "en",
 		GitDir:       "/tmp",
+		GraphQL:      true,
+		Concurrency:  4,
 	}
 }
 
@@ -49,6 +136,25 @@ func (c *Config) Read(path string) error {
 	return errors.Trace(err)
 }
 
+// CachePath returns the directory of API response cache, it's under GitDir by default
+func (c *Config) CachePath() string {
+	if c.CacheDir != "" {
+		return c.CacheDir
+	}
+	return path.Join(c.GitDir, "releaser-cache")
+}
+
+// ReleaseNoteLanguages returns languages of release note files, PullLanguage is the first
+func (c *Config) ReleaseNoteLanguages() []string {
+	langs := []string{c.PullLanguage}
+	for _, lang := range c.Languages {
+		if lang != c.PullLanguage {
+			langs = append(langs, lang)
+		}
+	}
+	return langs
+}
+
 // Print Config
 func (c *Config) Print(writer ...io.Writer) {
 	if len(writer) == 0 {
@@ -11,8 +11,34 @@ func TestConfig(t *testing.T) {
 	assert.Equal(t, cfg.Read("../config.example.toml"), nil, "read config")
 	assert.Equal(t, cfg.Products, []Product{
 		{
-			Name:  "tidb",
-			Repos: []string{"pingcap/tidb", "tikv/tikv", "pingcap/pd"},
+			Name: "tidb",
+			Repos: []string{"pingcap/tidb", "tikv/tikv", "pingcap/pd", "pingcap/tics",
+				"pingcap/br", "pingcap/dumpling", "pingcap/tidb-lightning", "pingcap/ticdc"},
+			Rename: map[string]string{
+				"pingcap/tics":           "PingCAP/TiFlash",
+				"pingcap/tidb":           "PingCAP/TiDB",
+				"tikv/tikv":              "TiKV/TiKV",
+				"pingcap/pd":             "PingCAP/PD",
+				"pingcap/br":             "PingCAP/BR",
+				"pingcap/dumpling":       "PingCAP/Dumpling",
+				"pingcap/tidb-lightning": "PingCAP/Lightning",
+				"pingcap/ticdc":          "PingCAP/TiCDC",
+			},
+			Structure: []string{
+				"pingcap/tidb",
+				"tikv/tikv",
+				"pingcap/pd",
+				"pingcap/tics",
+				"Tools: pingcap/br, pingcap/dumpling, pingcap/tidb-lightning, pingcap/ticdc",
+			},
+			Label2Type: map[string]string{
+				"compatibility-breaker": "Compatibility Changes",
+				"type/bug-fix":          "Bug Fixes",
+				"type/new-feature":      "New Features",
+			},
+			TypeOrder: []string{"Compatibility Changes", "New Features", "Improvements", "Bug Fixes"},
+			NoteOrder: "number",
 		},
 	}, "read config")
+	assert.Equal(t, len(cfg.Forges), 0, "forges")
 }
@@ -8,18 +8,39 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/you06/releaser/config"
 	"github.com/you06/releaser/manager"
+	"github.com/you06/releaser/pkg/httpcache"
 	"github.com/you06/releaser/pkg/types"
 )
 
 const (
 	nmVersion = "version"
 	nmConfig  = "config"
+	nmFormat  = "format"
+	nmDryRun  = "dry-run"
+	nmOutput  = "output"
+	nmNoCache = "no-cache"
+	nmPost    = "post"
+	nmFrom    = "from"
+	nmTo      = "to"
+	nmFix     = "fix"
 )
 
 var (
 	// common args
 	version    string
 	configPath string
+	noCache    bool
+	// check-module args
+	format string
+	// generate-release-note args
+	dryRun    bool
+	outputDir string
+	fromRef   string
+	toRef     string
+	// lint-notes args
+	post string
+	// check-milestone args
+	fix bool
 )
 
 func main() {
@@ -51,12 +72,70 @@ func main() {
 			runWithSubCommand(types.SubCmdGenerateReleaseNote)
 		},
 	}
+	generateReleaseNoteCmd.Flags().BoolVar(&dryRun, nmDryRun, false, "render release notes and print the diff without touching any remote")
+	generateReleaseNoteCmd.Flags().StringVar(&outputDir, nmOutput, "", "directory to write rendered release notes in dry run, stdout by default")
+	generateReleaseNoteCmd.Flags().StringVar(&fromRef, nmFrom, "", "collect pulls by commits after this ref instead of milestone, requires --to")
+	generateReleaseNoteCmd.Flags().StringVar(&toRef, nmTo, "", "collect pulls by commits until this ref instead of milestone, it's the version if --version is not set")
+
+	var releaseNotesCmd = &cobra.Command{
+		Use:   types.SubCmdReleaseNotes,
+		Short: "Check release notes of pulls in milestone",
+		Run: func(cmd *cobra.Command, args []string) {
+			runWithSubCommand(types.SubCmdReleaseNotes)
+		},
+	}
+
+	var lintNotesCmd = &cobra.Command{
+		Use:   types.SubCmdLintNotes,
+		Short: "Lint release notes of open pulls in milestone, exit with non-zero code on problems",
+		Run: func(cmd *cobra.Command, args []string) {
+			runWithSubCommand(types.SubCmdLintNotes)
+		},
+	}
+	lintNotesCmd.Flags().StringVar(&post, nmPost, "", "post findings back to pulls, comment or check, check needs a GitHub App installation token")
+
+	var checkMilestoneCmd = &cobra.Command{
+		Use:   types.SubCmdCheckMilestone,
+		Short: "Check pulls in milestone against the history of release branch",
+		Run: func(cmd *cobra.Command, args []string) {
+			runWithSubCommand(types.SubCmdCheckMilestone)
+		},
+	}
+	checkMilestoneCmd.Flags().StringVar(&fromRef, nmFrom, "", "previous tag of release branch, the highest tag lower than version by default")
+	checkMilestoneCmd.Flags().BoolVar(&fix, nmFix, false, "add pulls without milestone on release branch into milestone, remove closed pulls from milestone")
+
+	var checkModuleCmd = &cobra.Command{
+		Use:   types.SubCmdCheckModule,
+		Short: "Check the module version consistency between repos",
+		Run: func(cmd *cobra.Command, args []string) {
+			runWithSubCommand(types.SubCmdCheckModule)
+		},
+	}
+	checkModuleCmd.Flags().StringVar(&format, nmFormat, "table", "report format, table, json or markdown")
 
 	rootCmd.AddCommand(subCmdPRListCmd)
 	rootCmd.AddCommand(generateReleaseNoteCmd)
+	rootCmd.AddCommand(releaseNotesCmd)
+	rootCmd.AddCommand(lintNotesCmd)
+	rootCmd.AddCommand(checkMilestoneCmd)
+	rootCmd.AddCommand(checkModuleCmd)
+
+	var cacheCmd = &cobra.Command{
+		Use:   types.SubCmdCache,
+		Short: "Manage the on-disk cache of API responses",
+	}
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   types.SubCmdCacheClear,
+		Short: "Remove all cached API responses",
+		Run: func(cmd *cobra.Command, args []string) {
+			clearCache()
+		},
+	})
+	rootCmd.AddCommand(cacheCmd)
 
 	rootCmd.PersistentFlags().StringVar(&configPath, nmConfig, "./config.toml", "config file")
 	rootCmd.PersistentFlags().StringVar(&version, nmVersion, "", "release version")
+	rootCmd.PersistentFlags().BoolVar(&noCache, nmNoCache, false, "fetch everything from API without the on-disk cache")
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -71,7 +150,15 @@ func runWithSubCommand(cmd string) {
 	}
 
 	m, err := manager.New(cfg, &manager.Option{
-		Version: version,
+		Version:   version,
+		Format:    format,
+		DryRun:    dryRun,
+		OutputDir: outputDir,
+		NoCache:   noCache,
+		From:      fromRef,
+		To:        toRef,
+		Fix:       fix,
+		Post:      post,
 	})
 	if err != nil {
 		log.Fatalf("%+v", err)
@@ -81,3 +168,15 @@ func runWithSubCommand(cmd string) {
 		log.Fatalf("%+v\n", err)
 	}
 }
+
+func clearCache() {
+	cfg := config.New()
+	if err := cfg.Read(configPath); err != nil {
+		log.Fatalf("%+v", err)
+	}
+	removed, err := httpcache.Clear(cfg.CachePath())
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+	fmt.Printf("removed %d cached responses from %s\n", removed, cfg.CachePath())
+}
@@ -0,0 +1,245 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/you06/releaser/pkg/forge"
+	"github.com/you06/releaser/pkg/semver"
+	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
+)
+
+// milestoneDrift is the difference between pulls in milestone and pulls on release branch of a repo
+type milestoneDrift struct {
+	Repo      types.Repo
+	Milestone *github.Milestone
+	Branch    string
+	From      string
+	// NotOnBranch are pulls in milestone but not reachable on branch
+	NotOnBranch []*github.PullRequest
+	// NotInMilestone are pulls reachable on branch but not in milestone
+	NotInMilestone []*github.PullRequest
+	// Fixed are numbers of pulls whose milestones are fixed
+	Fixed map[int]bool
+}
+
+func (m *Manager) runCheckMilestone(ctx context.Context) error {
+	drifts := make([]*milestoneDrift, len(m.Repos))
+	err := utils.Parallel(ctx, m.Config.Concurrency, len(m.Repos), func(ctx context.Context, i int) error {
+		drift, err := m.checkRepoMilestone(ctx, m.Repos[i])
+		drifts[i] = drift
+		return errors.Trace(err)
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	if m.Opt.Fix {
+		for _, drift := range drifts {
+			if drift == nil {
+				continue
+			}
+			if err := m.fixMilestone(ctx, drift); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+
+	unfixed, err := writeMilestoneDrifts(os.Stdout, drifts)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if unfixed > 0 {
+		return errors.Errorf("%d pulls differ between milestone and release branch", unfixed)
+	}
+	return nil
+}
+
+// checkRepoMilestone diffs pulls in milestone with pulls on release branch since the previous tag,
+// repo without the milestone is skipped
+func (m *Manager) checkRepoMilestone(ctx context.Context, repo types.Repo) (*milestoneDrift, error) {
+	milestone, err := m.PullCollector.GetVersionMilestone(ctx, repo, m.Opt.Version)
+	if err != nil {
+		if strings.Contains(err.Error(), "milestone not found") {
+			fmt.Printf("No milestone %s in %s\n", m.Opt.Version, repo)
+			return nil, nil
+		}
+		return nil, errors.Trace(err)
+	}
+	_, milestonePulls, err := m.PullCollector.ListAllMilestoneContents(ctx, repo, milestone)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	branch, err := m.Branches.Resolve(ctx, repo, m.Opt.Version)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	drift := milestoneDrift{
+		Repo:      repo,
+		Milestone: milestone,
+		Branch:    branch,
+		From:      m.Opt.From,
+		Fixed:     make(map[int]bool),
+	}
+	if drift.From == "" {
+		if drift.From, err = m.previousTag(ctx, repo, m.Opt.Version); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	commits, err := m.listRangeCommits(ctx, repo, drift.From, drift.Branch)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	branchPulls, err := m.PullCollector.ListCommitPulls(ctx, repo, commits)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	onBranch := make(map[int]bool)
+	for _, pull := range branchPulls {
+		onBranch[pull.GetNumber()] = true
+	}
+	inMilestone := make(map[int]bool)
+	for _, pull := range milestonePulls {
+		inMilestone[pull.GetNumber()] = true
+		if !onBranch[pull.GetNumber()] {
+			drift.NotOnBranch = append(drift.NotOnBranch, pull)
+		}
+	}
+	for _, pull := range branchPulls {
+		if !inMilestone[pull.GetNumber()] {
+			drift.NotInMilestone = append(drift.NotInMilestone, pull)
+		}
+	}
+	return &drift, nil
+}
+
+// previousTag finds the highest version tag lower than version
+func (m *Manager) previousTag(ctx context.Context, repo types.Repo, version string) (string, error) {
+	current, err := semver.Parse(version)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	refs, err := m.Forges.For(repo).ListRefs(ctx, repo, "tags/")
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	var (
+		tag     string
+		highest semver.Version
+	)
+	for _, ref := range refs {
+		if !strings.HasPrefix(ref.GetRef(), "refs/tags/") {
+			continue
+		}
+		name := strings.TrimPrefix(ref.GetRef(), "refs/tags/")
+		v, err := semver.Parse(name)
+		if err != nil || v.Compare(current) >= 0 {
+			continue
+		}
+		if tag == "" || v.Compare(highest) > 0 {
+			tag, highest = name, v
+		}
+	}
+	if tag == "" {
+		return "", errors.Errorf("no tag before %s in %s", version, repo)
+	}
+	return tag, nil
+}
+
+// fixMilestone adds pulls on branch without milestone into the milestone,
+// and removes closed pulls which are not merged from the milestone. Other pulls need manual check.
+func (m *Manager) fixMilestone(ctx context.Context, drift *milestoneDrift) error {
+	setter, ok := m.Forges.For(drift.Repo).(forge.MilestoneSetter)
+	if !ok {
+		return errors.Errorf("forge of %s can not set milestones", drift.Repo)
+	}
+	set := func(pull *github.PullRequest, milestone int) error {
+		ctx, cancel := utils.NewTimeoutContext(ctx)
+		defer cancel()
+		if err := setter.SetPullMilestone(ctx, drift.Repo, pull.GetNumber(), milestone); err != nil {
+			return errors.Trace(err)
+		}
+		drift.Fixed[pull.GetNumber()] = true
+		return nil
+	}
+	for _, pull := range drift.NotInMilestone {
+		if pull.Milestone == nil {
+			if err := set(pull, drift.Milestone.GetNumber()); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	for _, pull := range drift.NotOnBranch {
+		if pull.GetState() == "closed" && !pull.GetMerged() {
+			if err := set(pull, 0); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeMilestoneDrifts prints drifts of every repo, and returns the number of pulls not fixed
+func writeMilestoneDrifts(w io.Writer, drifts []*milestoneDrift) (int, error) {
+	var (
+		b       strings.Builder
+		unfixed int
+	)
+	writePulls := func(title string, pulls []*github.PullRequest, drift *milestoneDrift, status func(*github.PullRequest) string) {
+		if len(pulls) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "  %s:\n", title)
+		for _, pull := range pulls {
+			s := status(pull)
+			if drift.Fixed[pull.GetNumber()] {
+				s += ", fixed"
+			} else {
+				unfixed++
+			}
+			fmt.Fprintf(&b, "    #%d %s (%s)\n", pull.GetNumber(), pull.GetTitle(), s)
+		}
+	}
+	for _, drift := range drifts {
+		if drift == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s milestone %s, %s since %s\n", drift.Repo, drift.Milestone.GetTitle(), drift.Branch, drift.From)
+		if len(drift.NotOnBranch) == 0 && len(drift.NotInMilestone) == 0 {
+			b.WriteString("  consistent\n")
+			continue
+		}
+		writePulls("In milestone but not on "+drift.Branch, drift.NotOnBranch, drift, milestonePullStatus)
+		writePulls("On "+drift.Branch+" but not in milestone", drift.NotInMilestone, drift, branchPullStatus)
+	}
+	_, err := io.WriteString(w, b.String())
+	return unfixed, errors.Trace(err)
+}
+
+func milestonePullStatus(pull *github.PullRequest) string {
+	switch {
+	case pull.GetMerged():
+		return "merged into " + pull.GetBase().GetRef()
+	case pull.GetState() == "closed":
+		return "closed without merge"
+	default:
+		return pull.GetState()
+	}
+}
+
+func branchPullStatus(pull *github.PullRequest) string {
+	if pull.Milestone == nil {
+		return "no milestone"
+	}
+	return "milestone " + pull.GetMilestone().GetTitle()
+}
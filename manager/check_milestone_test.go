@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you06/releaser/pkg/githubtest"
+	"github.com/you06/releaser/pkg/types"
+)
+
+func TestCheckMilestone(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	milestone := env.server.AddMilestone(testTiDB, "v4.0.6", "open")
+	next := env.server.AddMilestone(testTiDB, "v4.0.7", "open")
+	open := testPull(101, milestone, "release-4.0", "")
+	open.State, open.Merged = github.String("open"), github.Bool(false)
+	closed := testPull(102, milestone, "release-4.0", "")
+	closed.Merged = github.Bool(false)
+	noMilestone := testPull(103, nil, "release-4.0", "")
+	env.server.AddPull(testTiDB, testPull(100, milestone, "release-4.0", ""))
+	env.server.AddPull(testTiDB, open)
+	env.server.AddPull(testTiDB, closed)
+	env.server.AddPull(testTiDB, noMilestone)
+	env.server.AddPull(testTiDB, testPull(104, next, "release-4.0", ""))
+	for _, ref := range []string{"refs/tags/v4.0.4", "refs/tags/v4.0.5", "refs/tags/v4.0.7"} {
+		env.server.AddRef(testTiDB, ref, "sha")
+	}
+	env.server.AddComparison(testTiDB, "v4.0.5", "release-4.0", 3,
+		githubtest.Commit("a1", "executor: fix panic (#100)"),
+		githubtest.Commit("a2", "planner: fix a bug (#103)"),
+		githubtest.Commit("a3", "ddl: fix a bug (#104)"))
+
+	m := env.newManager(t, "v4.0.6")
+	err := m.Run(types.SubCmdCheckMilestone)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "4 pulls differ")
+	tags := env.server.CountRequests("GET /repos/pingcap/tidb/git/refs/tags")
+	assert.True(t, tags > 0)
+	assert.Equal(t, env.server.CountRequests("GET /repos/pingcap/tidb/git/refs"),
+		tags+env.server.CountRequests("GET /repos/pingcap/tidb/git/refs/heads"), "only tags and branches are listed")
+
+	m.Opt.Fix = true
+	err = m.Run(types.SubCmdCheckMilestone)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "2 pulls differ")
+	assert.Equal(t, noMilestone.GetMilestone().GetNumber(), milestone.GetNumber())
+	assert.Nil(t, closed.Milestone)
+
+	m.Opt.Fix = false
+	err = m.Run(types.SubCmdCheckMilestone)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "2 pulls differ", "open pull and pull in other milestone are left")
+}
+
+func TestCheckMilestoneClone(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	seed := path.Join(env.dir, "seed-tidb")
+	require.Nil(t, os.MkdirAll(seed, 0755))
+	gitRun(t, seed, "init", "-q")
+	gitRun(t, seed, "checkout", "-q", "-b", "master")
+	for i, message := range []string{"init", "executor: fix panic (#100)", "planner: fix a bug (#103)"} {
+		require.Nil(t, ioutil.WriteFile(path.Join(seed, "file"), []byte(message), 0644))
+		gitRun(t, seed, "add", "file")
+		gitRun(t, seed, "commit", "-q", "-m", message)
+		if i == 0 {
+			gitRun(t, seed, "tag", "v4.0.5")
+			// the release branch is not checked out in a fresh clone
+			gitRun(t, seed, "checkout", "-q", "-b", "release-4.0")
+		}
+	}
+	gitRun(t, seed, "checkout", "-q", "master")
+	gitRun(t, env.dir, "clone", "-q", "--bare", seed, env.remote(testTiDB))
+
+	milestone := env.server.AddMilestone(testTiDB, "v4.0.6", "open")
+	env.server.AddPull(testTiDB, testPull(100, milestone, "release-4.0", ""))
+	env.server.AddPull(testTiDB, testPull(103, nil, "release-4.0", ""))
+	env.server.AddRef(testTiDB, "refs/tags/v4.0.5", "sha")
+	// too many commits for compare API
+	env.server.AddComparison(testTiDB, "v4.0.5", "release-4.0", 300)
+
+	m := env.newManager(t, "v4.0.6")
+	err := m.Run(types.SubCmdCheckMilestone)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "1 pulls differ")
+}
+
+func TestWriteMilestoneDrifts(t *testing.T) {
+	var (
+		b    = new(strings.Builder)
+		open = testPull(101, nil, "release-4.0", "")
+	)
+	open.State, open.Merged = github.String("open"), github.Bool(false)
+	unfixed, err := writeMilestoneDrifts(b, []*milestoneDrift{
+		nil,
+		{
+			Repo:           testTiDB,
+			Milestone:      &github.Milestone{Title: github.String("v4.0.6")},
+			Branch:         "release-4.0",
+			From:           "v4.0.5",
+			NotOnBranch:    []*github.PullRequest{open, testPull(102, nil, "master", "")},
+			NotInMilestone: []*github.PullRequest{testPull(103, nil, "release-4.0", "")},
+			Fixed:          map[int]bool{103: true},
+		},
+		{Repo: testPD, Milestone: &github.Milestone{Title: github.String("v4.0.6")}, Branch: "release-4.0", From: "v4.0.5"},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, unfixed, 2)
+	assert.Equal(t, b.String(), `pingcap/tidb milestone v4.0.6, release-4.0 since v4.0.5
+  In milestone but not on release-4.0:
+    #101 title (open)
+    #102 title (merged into master)
+  On release-4.0 but not in milestone:
+    #103 title (no milestone, fixed)
+pingcap/pd milestone v4.0.6, release-4.0 since v4.0.5
+  consistent
+`)
+}
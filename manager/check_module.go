@@ -1,52 +1,231 @@
 package manager
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/juju/errors"
+	"github.com/olekukonko/tablewriter"
+	"github.com/you06/releaser/pkg/semver"
 	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
 )
 
-type packageVersion struct {
-	version string
-	repo    types.Repo
+const (
+	formatTable    = "table"
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+
+	// iconWinner marks pins with the highest version
+	iconWinner = "√"
+)
+
+// moduleReport is the drift report of dependencies through repos
+type moduleReport struct {
+	Version string         `json:"version"`
+	Sources []moduleSource `json:"sources"`
+	Drifts  []moduleDrift  `json:"drifts"`
+}
+
+// moduleSource is a dependency file found in repo
+type moduleSource struct {
+	Repo string `json:"repo"`
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// moduleDrift is a dependency pinned to different versions
+type moduleDrift struct {
+	Name string      `json:"name"`
+	Pins []modulePin `json:"pins"`
 }
 
-func (m *Manager) runCheckModule() error {
+// modulePin is the version pinned by a repo
+type modulePin struct {
+	Repo    string `json:"repo"`
+	Version string `json:"version"`
+	Winner  bool   `json:"winner"`
+}
+
+func checkFormat(format string) error {
+	switch format {
+	case "", formatTable, formatJSON, formatMarkdown:
+		return nil
+	default:
+		return errors.Errorf("invalid format %s", format)
+	}
+}
+
+func (m *Manager) runCheckModule(ctx context.Context) error {
+	if err := checkFormat(m.Opt.Format); err != nil {
+		return errors.Trace(err)
+	}
 	var (
-		packages   []*types.Package
-		versionMap = make(map[string]packageVersion)
+		packages     []*types.Package
+		repoPackages = make([][]*types.Package, len(m.Repos))
 	)
 
-	for _, repo := range m.Repos {
-		batch, err := m.DependencyCollector.GetDependencies(repo, m.Opt.Version)
-		if err != nil {
-			return errors.Trace(err)
-		}
+	err := utils.Parallel(ctx, m.Config.Concurrency, len(m.Repos), func(ctx context.Context, i int) error {
+		batch, err := m.DependencyCollector.GetDependencies(ctx, m.Repos[i], m.Opt.Version)
+		repoPackages[i] = batch
+		return errors.Trace(err)
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, batch := range repoPackages {
 		packages = append(packages, batch...)
 	}
 
-	for _, p := range packages {
-		fmt.Printf("%s %s: %s\n", p.Repo, p.Type, p.URL)
-	}
-	fmt.Println("-----------------------")
+	report := buildModuleReport(m.Opt.Version, packages)
+	return errors.Trace(report.Render(os.Stdout, m.Opt.Format))
+}
+
+func buildModuleReport(version string, packages []*types.Package) moduleReport {
+	var (
+		report = moduleReport{Version: version}
+		names  []string
+		pins   = make(map[string][]modulePin)
+	)
 
 	for _, p := range packages {
+		report.Sources = append(report.Sources, moduleSource{
+			Repo: p.Repo.String(),
+			Type: p.Type,
+			URL:  p.URL,
+		})
 		for _, dependency := range p.Dependencies {
-			existVersion, ok := versionMap[dependency.Name]
-			if !ok {
-				versionMap[dependency.Name] = packageVersion{
-					version: dependency.Version,
-					repo:    p.Repo,
-				}
-			} else {
-				if existVersion.version != dependency.Version {
-					fmt.Printf("%s | %s: %s, %s: %s\n", dependency.Name,
-						existVersion.repo, existVersion.version, p.Repo, dependency.Version)
-				}
+			if _, ok := pins[dependency.Name]; !ok {
+				names = append(names, dependency.Name)
+			}
+			pins[dependency.Name] = append(pins[dependency.Name], modulePin{
+				Repo:    p.Repo.String(),
+				Version: dependency.Version,
+			})
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		if !isDrift(pins[name]) {
+			continue
+		}
+		report.Drifts = append(report.Drifts, moduleDrift{
+			Name: name,
+			Pins: markWinner(pins[name]),
+		})
+	}
+	return report
+}
+
+func isDrift(pins []modulePin) bool {
+	for _, pin := range pins {
+		if pin.Version != pins[0].Version {
+			return true
+		}
+	}
+	return false
+}
+
+// markWinner marks all pins with the highest version by semver ordering
+func markWinner(pins []modulePin) []modulePin {
+	highest := pins[0].Version
+	for _, pin := range pins[1:] {
+		if semver.Compare(pin.Version, highest) > 0 {
+			highest = pin.Version
+		}
+	}
+	for i := range pins {
+		pins[i].Winner = semver.Compare(pins[i].Version, highest) == 0
+	}
+	return pins
+}
+
+// Render writes report in given format
+func (r moduleReport) Render(w io.Writer, format string) error {
+	switch format {
+	case "", formatTable:
+		return errors.Trace(r.renderTable(w))
+	case formatJSON:
+		return errors.Trace(r.renderJSON(w))
+	case formatMarkdown:
+		return errors.Trace(r.renderMarkdown(w))
+	default:
+		return errors.Errorf("invalid format %s", format)
+	}
+}
+
+func (r moduleReport) renderTable(w io.Writer) error {
+	var (
+		tableString strings.Builder
+		table       = tablewriter.NewWriter(&tableString)
+	)
+	for _, source := range r.Sources {
+		if _, err := fmt.Fprintf(w, "%s %s: %s\n", source.Repo, source.Type, source.URL); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	table.SetHeader([]string{"Dependency", "Repo", "Version", "Winner"})
+	table.SetRowLine(true)
+	for _, drift := range r.Drifts {
+		for i, pin := range drift.Pins {
+			// auto merge would merge cells of other columns across dependencies, so only the name is blanked
+			name := ""
+			if i == 0 {
+				name = drift.Name
 			}
+			table.Append([]string{name, pin.Repo, pin.Version, winnerIcon(pin.Winner)})
 		}
 	}
+	table.Render()
+	_, err := fmt.Fprintln(w, tableString.String())
+	return errors.Trace(err)
+}
+
+func (r moduleReport) renderJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return errors.Trace(encoder.Encode(r))
+}
+
+func (r moduleReport) renderMarkdown(w io.Writer) error {
+	var b strings.Builder
 
-	return nil
+	fmt.Fprintf(&b, "### Module drift of %s\n\n", r.Version)
+	for _, source := range r.Sources {
+		fmt.Fprintf(&b, "- %s [%s](%s)\n", source.Repo, source.Type, source.URL)
+	}
+	if len(r.Sources) > 0 {
+		b.WriteString("\n")
+	}
+	if len(r.Drifts) == 0 {
+		b.WriteString("No drifting dependency.\n")
+		_, err := io.WriteString(w, b.String())
+		return errors.Trace(err)
+	}
+	b.WriteString("| Dependency | Repo | Version | Winner |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, drift := range r.Drifts {
+		for i, pin := range drift.Pins {
+			name := ""
+			if i == 0 {
+				name = fmt.Sprintf("`%s`", drift.Name)
+			}
+			fmt.Fprintf(&b, "| %s | %s | `%s` | %s |\n", name, pin.Repo, pin.Version, winnerIcon(pin.Winner))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return errors.Trace(err)
+}
+
+func winnerIcon(winner bool) string {
+	if winner {
+		return iconWinner
+	}
+	return ""
 }
@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/you06/releaser/pkg/types"
+)
+
+func TestBuildModuleReport(t *testing.T) {
+	tidb := types.Repo{Owner: "pingcap", Repo: "tidb"}
+	pd := types.Repo{Owner: "pingcap", Repo: "pd"}
+	report := buildModuleReport("v3.0.9", []*types.Package{
+		{Repo: tidb, Type: "go.mod", Dependencies: []types.Dependency{
+			{Name: "go.uber.org/zap", Version: "v1.9.1"},
+			{Name: "github.com/gogo/protobuf", Version: "v1.2.0"},
+		}},
+		{Repo: pd, Type: "go.mod", Dependencies: []types.Dependency{
+			{Name: "github.com/gogo/protobuf", Version: "v1.2.0"},
+			{Name: "go.uber.org/zap", Version: "v1.10.0"},
+		}},
+	})
+
+	assert.Equal(t, len(report.Sources), 2, "sources")
+	assert.Equal(t, report.Drifts, []moduleDrift{
+		{
+			Name: "go.uber.org/zap",
+			Pins: []modulePin{
+				{Repo: "pingcap/tidb", Version: "v1.9.1"},
+				{Repo: "pingcap/pd", Version: "v1.10.0", Winner: true},
+			},
+		},
+	}, "drifts")
+
+	var b strings.Builder
+	assert.Nil(t, report.Render(&b, formatMarkdown))
+	assert.Contains(t, b.String(), "| `go.uber.org/zap` | pingcap/tidb | `v1.9.1` |  |\n|  | pingcap/pd | `v1.10.0` | √ |")
+	assert.NotNil(t, report.Render(&b, "yaml"), "invalid format")
+}
+
+func TestRenderModuleTable(t *testing.T) {
+	report := moduleReport{
+		Version: "v3.0.9",
+		Drifts: []moduleDrift{
+			{Name: "github.com/gogo/protobuf", Pins: []modulePin{
+				{Repo: "pingcap/tidb", Version: "v1.2.0"},
+				{Repo: "pingcap/pd", Version: "v1.3.0", Winner: true},
+			}},
+			{Name: "go.uber.org/zap", Pins: []modulePin{
+				{Repo: "pingcap/tidb", Version: "v1.3.0", Winner: true},
+				{Repo: "pingcap/pd", Version: "v1.2.0"},
+			}},
+		},
+	}
+	var b strings.Builder
+	assert.Nil(t, report.Render(&b, formatTable))
+	// cells of different dependencies are not merged
+	assert.Equal(t, strings.Count(b.String(), "pingcap/tidb"), 2)
+	assert.Equal(t, strings.Count(b.String(), "v1.3.0"), 2)
+	assert.Equal(t, strings.Count(b.String(), "go.uber.org/zap"), 1)
+}
+
+func TestCheckModuleFormat(t *testing.T) {
+	// format is checked before dependencies are fetched
+	m := Manager{Opt: &Option{Format: "yaml"}}
+	err := m.runCheckModule(context.Background())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid format yaml")
+}
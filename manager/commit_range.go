@@ -0,0 +1,101 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/you06/releaser/pkg/git"
+	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
+)
+
+// rangeMode collects pulls by commits between Opt.From and Opt.To instead of milestones
+func (m *Manager) rangeMode() bool {
+	return m.Opt.From != "" || m.Opt.To != ""
+}
+
+// initRange checks the commit range, the version of release notes is Opt.To if it's not set
+func (m *Manager) initRange() error {
+	if !m.rangeMode() {
+		return nil
+	}
+	if m.Opt.From == "" || m.Opt.To == "" {
+		return errors.New("both from and to are required to collect pulls by commits")
+	}
+	if m.Opt.Version == "" {
+		m.Opt.Version = m.Opt.To
+	}
+	return nil
+}
+
+// rangeMilestone is the milestone which stands for the commit range in the generation pipeline
+func (m *Manager) rangeMilestone() *github.Milestone {
+	return &github.Milestone{Title: github.String(m.Opt.Version)}
+}
+
+// listProductRangePulls lists pulls between from and to of every repo in parallel, in product.Repos order
+func (m *Manager) listProductRangePulls(ctx context.Context, product types.Product) ([][]*github.PullRequest, error) {
+	res := make([][]*github.PullRequest, len(product.Repos))
+	err := utils.Parallel(ctx, m.Config.Concurrency, len(product.Repos), func(ctx context.Context, i int) error {
+		pulls, err := m.listRepoRangePulls(ctx, product.Repos[i])
+		res[i] = pulls
+		return errors.Trace(err)
+	})
+	return res, errors.Trace(err)
+}
+
+// listRepoRangePulls lists pulls which introduced commits between from and to, repo without the refs has no pulls
+func (m *Manager) listRepoRangePulls(ctx context.Context, repo types.Repo) ([]*github.PullRequest, error) {
+	commits, err := m.listRangeCommits(ctx, repo, m.Opt.From, m.Opt.To)
+	if err != nil {
+		if !isMissingRef(err) {
+			return nil, errors.Trace(err)
+		}
+		fmt.Printf("Refs %s and %s not found in %s, %v\n", m.Opt.From, m.Opt.To, repo, err)
+		return nil, nil
+	}
+	pulls, err := m.PullCollector.ListCommitPulls(ctx, repo, commits)
+	return pulls, errors.Trace(err)
+}
+
+// listRangeCommits lists commits between from and to by compare API,
+// or by a local clone if the forge can not compare or there are too many commits
+func (m *Manager) listRangeCommits(ctx context.Context, repo types.Repo, from, to string) ([]*github.RepositoryCommit, error) {
+	commits, complete, err := m.PullCollector.CompareCommits(ctx, repo, from, to)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if complete {
+		return commits, nil
+	}
+
+	g := git.New(m.Config, &git.Config{
+		Forge: m.Forges.For(repo),
+		User:  m.User,
+		Base:  repo,
+		Head:  repo,
+		Dir:   fmt.Sprintf("range-%s-%s", repo.Owner, repo.Repo),
+	})
+	if err := g.Clear(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := g.Clone(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer func() {
+		if err := g.Clear(); err != nil {
+			log.Error(err)
+		}
+	}()
+	commits, err = g.LogRange(from, to)
+	return commits, errors.Trace(err)
+}
+
+// isMissingRef checks if err is caused by refs not in repo, from compare API or git log
+func isMissingRef(err error) bool {
+	return strings.Contains(err.Error(), "404 Not Found") || strings.Contains(err.Error(), "unknown revision")
+}
@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you06/releaser/pkg/githubtest"
+	"github.com/you06/releaser/pkg/types"
+)
+
+func TestGenerateReleaseNoteRange(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	milestone := env.server.AddMilestone(testTiDB, "v4.0.6", "open")
+	env.server.AddPull(testTiDB, testPull(100, nil, "master", "### Release note\n- fix a panic in executor", "type/bug-fix"))
+	env.server.AddPull(testTiDB, testPull(101, nil, "master", "### Release note\n- support new syntax"))
+	env.server.AddPull(testTiDB, testPull(102, nil, "master", "### Release note\n- fix a bug in planner", "type/bug-fix"))
+	env.server.AddPull(testTiDB, testPull(103, milestone, "release-4.0", "### Release note\n- not in range"))
+	env.server.AddComparison(testTiDB, "v4.0.5", "v4.0.6", 4,
+		githubtest.Commit("a1", "executor: fix panic (#100)"),
+		githubtest.Commit("a2", "Merge pull request #101 from dev/syntax\n\nsupport new syntax"),
+		githubtest.Commit("a3", "planner: fix a bug"),
+		githubtest.Commit("a4", "executor: fix panic again (#100)"))
+	mergedAt := time.Now()
+	env.server.SetCommitPulls(testTiDB, "a3", &github.PullRequest{Number: github.Int(102), MergedAt: &mergedAt})
+
+	m := env.newManager(t, "")
+	m.Opt.From, m.Opt.To = "v4.0.5", "v4.0.6"
+	require.Nil(t, m.Run(types.SubCmdGenerateReleaseNote))
+
+	content := gitRun(t, env.dir, "--git-dir", env.remote(types.Repo{Owner: testBot, Repo: testReleaseNote.Repo}),
+		"show", "update-4.0.6:tidb/4.0.6.md")
+	assert.Contains(t, content, "    - Fix a panic in executor [#100]")
+	assert.Contains(t, content, "    - Support new syntax [#101]")
+	assert.Contains(t, content, "    - Fix a bug in planner [#102]")
+	assert.NotContains(t, content, "#103")
+	assert.Equal(t, env.server.CountRequests("GET /repos/pingcap/tidb/commits/"), 1, "only commits without pull number use API")
+
+	m.Opt.From, m.Opt.To, m.Opt.Version = "", "v4.0.6", ""
+	assert.NotNil(t, m.Run(types.SubCmdGenerateReleaseNote))
+}
+
+func TestGenerateReleaseNoteRangeClone(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	seed := path.Join(env.dir, "seed-tidb")
+	require.Nil(t, os.MkdirAll(seed, 0755))
+	gitRun(t, seed, "init", "-q")
+	gitRun(t, seed, "checkout", "-q", "-b", "master")
+	for i, message := range []string{"init", "executor: fix panic (#100)", "planner: support new syntax (#101)"} {
+		require.Nil(t, ioutil.WriteFile(path.Join(seed, "file"), []byte(message), 0644))
+		gitRun(t, seed, "add", "file")
+		gitRun(t, seed, "commit", "-q", "-m", message)
+		if i == 0 {
+			gitRun(t, seed, "tag", "v4.0.5")
+		}
+	}
+	gitRun(t, seed, "tag", "v4.0.6")
+	gitRun(t, env.dir, "clone", "-q", "--bare", seed, env.remote(testTiDB))
+
+	env.server.AddPull(testTiDB, testPull(100, nil, "master", "### Release note\n- fix a panic in executor", "type/bug-fix"))
+	env.server.AddPull(testTiDB, testPull(101, nil, "master", "### Release note\n- support new syntax"))
+	// too many commits for compare API
+	env.server.AddComparison(testTiDB, "v4.0.5", "v4.0.6", 300)
+
+	m := env.newManager(t, "v4.0.6")
+	m.Opt.From, m.Opt.To = "v4.0.5", "v4.0.6"
+	require.Nil(t, m.Run(types.SubCmdGenerateReleaseNote))
+
+	content := gitRun(t, env.dir, "--git-dir", env.remote(types.Repo{Owner: testBot, Repo: testReleaseNote.Repo}),
+		"show", "update-4.0.6:tidb/4.0.6.md")
+	assert.Contains(t, content, "    - Fix a panic in executor [#100]")
+	assert.Contains(t, content, "    - Support new syntax [#101]")
+}
+
+func TestGenerateReleaseNoteRangeError(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	// the clone fails, which should not be taken as missing refs
+	env.server.AddComparison(testTiDB, "v4.0.5", "v4.0.6", 300)
+	m := env.newManager(t, "v4.0.6")
+	m.Opt.From, m.Opt.To = "v4.0.5", "v4.0.6"
+	require.NotNil(t, m.Run(types.SubCmdGenerateReleaseNote))
+	assert.Equal(t, len(env.server.CreatedPulls), 0)
+
+	assert.True(t, isMissingRef(errors.New("GET compare/v4.0.5...v4.0.6: 404 Not Found []")))
+	assert.True(t, isMissingRef(errors.New("git log v4.0.5..v4.0.6 failed, fatal: ambiguous argument 'v4.0.5..v4.0.6': unknown revision or path not in the working tree.")))
+	assert.False(t, isMissingRef(context.Canceled))
+}
@@ -1,125 +1,281 @@
 package manager
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
-	"regexp"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v30/github"
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/you06/releaser/pkg/diff"
 	"github.com/you06/releaser/pkg/git"
 	"github.com/you06/releaser/pkg/parser"
 	"github.com/you06/releaser/pkg/types"
 	"github.com/you06/releaser/pkg/utils"
 )
 
-var (
-	numPattern = regexp.MustCompile(`^.*?(\d+)$`)
-)
-
-func (m *Manager) runGenerateReleaseNote() error {
-	if err := m.initRepo(); err != nil {
+func (m *Manager) runGenerateReleaseNote(ctx context.Context) error {
+	if err := m.initRange(); err != nil {
 		return errors.Trace(err)
 	}
-	var errs []error
-
-	for _, project := range m.Products {
-		errs = append(errs, m.generateReleaseNoteProduct(project))
+	// dry run does not touch any remote, so there is no need to fork
+	if !m.Opt.DryRun {
+		if err := m.initRepo(ctx); err != nil {
+			return errors.Trace(err)
+		}
 	}
 
-	for _, err := range errs {
-		if err != nil {
+	for _, product := range m.Products {
+		if err := m.generateReleaseNoteProduct(ctx, product); err != nil {
 			return errors.Trace(err)
 		}
 	}
 	return nil
 }
 
-func (m *Manager) generateReleaseNoteProduct(product types.Product) error {
-	// Do not process empty product
-	if len(product.Repos) == 0 {
-		return nil
+// productMilestones gets target milestones of a product by the first repo
+func (m *Manager) productMilestones(ctx context.Context, product types.Product) ([]*github.Milestone, error) {
+	if m.Opt.Version == "all" {
+		milestones, err := m.PullCollector.ListAllOpenedMilestones(ctx, product.Repos[0])
+		return milestones, errors.Trace(err)
+	}
+	milestone, err := m.PullCollector.GetVersionMilestone(ctx, product.Repos[0], m.Opt.Version)
+	if err != nil {
+		return nil, errors.Trace(err)
 	}
+	return []*github.Milestone{milestone}, nil
+}
 
+// listProductPulls lists pulls of every milestone and repo in parallel,
+// the result is indexed by milestone first and then by repo in product.Repos order
+func (m *Manager) listProductPulls(ctx context.Context, product types.Product, milestones []*github.Milestone) ([][][]*github.PullRequest, error) {
 	var (
-		errs       []error
-		milestones []*github.Milestone
+		repoCount = len(product.Repos)
+		flat      = make([][]*github.PullRequest, len(milestones)*repoCount)
+		res       = make([][][]*github.PullRequest, len(milestones))
 	)
+	err := utils.Parallel(ctx, m.Config.Concurrency, len(flat), func(ctx context.Context, i int) error {
+		// milestones of other repos are found by the version in title, their titles may differ
+		version := m.PullCollector.MilestoneVersion(product.Repos[0], milestones[i/repoCount].GetTitle())
+		pulls, err := m.listRepoMilestonePulls(ctx, product.Repos[i%repoCount], version)
+		flat[i] = pulls
+		return errors.Trace(err)
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for i := range milestones {
+		res[i] = flat[i*repoCount : (i+1)*repoCount]
+	}
+	return res, nil
+}
 
-	// get target milestones
-	if m.Opt.Version == "all" {
-		// get milestones by first product
-		var err error
-		milestones, err = m.PullCollector.ListAllOpenedMilestones(product.Repos[0])
-		if err != nil {
-			return errors.Trace(err)
+// listRepoMilestonePulls lists pulls in the milestone of repo, repo without the milestone has no pulls
+func (m *Manager) listRepoMilestonePulls(ctx context.Context, repo types.Repo, version string) ([]*github.PullRequest, error) {
+	milestone, err := m.PullCollector.GetVersionMilestone(ctx, repo, version)
+	if err != nil {
+		if strings.Contains(err.Error(), "milestone not found") {
+			fmt.Printf("No milestone %s in %s\n", version, repo)
+			return nil, nil
 		}
-	} else {
-		milestone, err := m.PullCollector.GetVersionMilestone(product.Repos[0], m.Opt.Version)
+		return nil, errors.Trace(err)
+	}
+	_, pulls, err := m.PullCollector.ListAllMilestoneContents(ctx, repo, milestone)
+	return pulls, errors.Trace(err)
+}
+
+func (m *Manager) generateReleaseNoteProduct(ctx context.Context, product types.Product) error {
+	// Do not process empty product
+	if len(product.Repos) == 0 {
+		return nil
+	}
+
+	if m.rangeMode() {
+		pulls, err := m.listProductRangePulls(ctx, product)
 		if err != nil {
 			return errors.Trace(err)
 		}
-		milestones = append(milestones, milestone)
+		return errors.Trace(m.generateReleaseNoteProductMilestone(ctx, product, m.rangeMilestone(), pulls))
 	}
 
-	for _, milestone := range milestones {
-		errs = append(errs, m.generateReleaseNoteProductMilestone(product, milestone))
+	milestones, err := m.productMilestones(ctx, product)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	pulls, err := m.listProductPulls(ctx, product, milestones)
+	if err != nil {
+		return errors.Trace(err)
 	}
 
-	for _, err := range errs {
-		if err != nil {
+	for i, milestone := range milestones {
+		if err := m.generateReleaseNoteProductMilestone(ctx, product, milestone, pulls[i]); err != nil {
 			return errors.Trace(err)
 		}
 	}
 	return nil
 }
 
-func (m *Manager) generateReleaseNoteProductMilestone(product types.Product, milestone *github.Milestone) error {
-	releaseNotes, err := m.NoteCollector.ListReleaseNote(product, milestone.GetTitle())
+func (m *Manager) generateReleaseNoteProductMilestone(ctx context.Context, product types.Product, milestone *github.Milestone, repoPulls [][]*github.PullRequest) error {
+	releaseNotes, removed, err := m.collectReleaseNote(ctx, product, milestone, repoPulls)
 	if err != nil {
-		return errors.Errorf("get release notes error %+v\n", err)
+		return errors.Trace(err)
 	}
-	var defaultLangReleaseNote *parser.ReleaseNoteLang
-	for _, releaseNote := range releaseNotes {
-		if releaseNote.Lang == m.Config.PullLanguage {
-			defaultLangReleaseNote = &releaseNote
-			// break because the meomory address will be replaced by next item
-			// the pointer will indicate to the wrong item
-			break
+	for _, note := range removed {
+		fmt.Printf("%s#%d is removed from milestone %s, please check its release note\n",
+			note.Repo, note.PullNumber, milestone.GetTitle())
+	}
+	fmt.Print(translationCoverage(releaseNotes))
+	if m.Opt.DryRun {
+		for _, releaseNote := range releaseNotes {
+			if err := m.previewReleaseNote(ctx, product, releaseNote); err != nil {
+				return errors.Trace(err)
+			}
 		}
+		return nil
 	}
+	return errors.Trace(m.publishReleaseNote(ctx, product, milestone, releaseNotes, removed))
+}
 
-	if defaultLangReleaseNote == nil {
-		dir := strings.ReplaceAll(m.Config.ReleaseNotePath, "{product}", product.Name)
-		defaultLangReleaseNote = &parser.ReleaseNoteLang{
-			Name:               product.Name,
-			Lang:               m.Config.PullLanguage,
-			Path:               path.Join(dir, fmt.Sprintf("%s.md", strings.TrimLeft(m.Opt.Version, "v"))),
-			Version:            milestone.GetTitle(),
-			ReleaseNoteClasses: make(map[string][]parser.RepoReleaseNotes),
-			Structure:          product.Structure,
-		}
+// collectReleaseNote makes release notes of a milestone in every language from pulls of repos,
+// and merges them into existing release note files. The first one is in PullLanguage,
+// notes in other languages are copied from it and marked to be translated.
+// Notes of pulls which are removed from milestone are flagged and returned.
+func (m *Manager) collectReleaseNote(ctx context.Context, product types.Product, milestone *github.Milestone, repoPulls [][]*github.PullRequest) ([]*parser.ReleaseNoteLang, []parser.ReleaseNote, error) {
+	existing, err := m.NoteCollector.ListReleaseNote(ctx, product, milestone.GetTitle())
+	if err != nil {
+		return nil, nil, errors.Errorf("get release notes error %+v\n", err)
 	}
 
-	for _, repo := range product.Repos {
+	dir := strings.ReplaceAll(m.Config.ReleaseNotePath, "{product}", product.Name)
+	version := strings.TrimLeft(m.Opt.Version, "v")
+	generated := &parser.ReleaseNoteLang{
+		Name:               product.Name,
+		Lang:               m.Config.PullLanguage,
+		Path:               path.Join(dir, fmt.Sprintf("%s.md", version)),
+		Version:            milestone.GetTitle(),
+		ReleaseNoteClasses: make(map[string][]parser.RepoReleaseNotes),
+		Structure:          product.Structure,
+		TypeOrder:          product.TypeOrder,
+	}
+	for i, repo := range product.Repos {
 		rename, ok := product.Renames[repo]
 		if !ok {
 			rename = repo
 		}
-		if err := m.makeReleaseNoteRepoMilestone(product, repo, rename, defaultLangReleaseNote); err != nil {
+		if err := m.makeReleaseNoteRepoMilestone(ctx, product, repo, rename, milestone, repoPulls[i], generated); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+	}
+	generated.SortNotes(product.NoteOrder)
+
+	inMilestone := make(map[types.Repo]map[int]bool)
+	for i, repo := range product.Repos {
+		inMilestone[repo] = make(map[int]bool)
+		for _, pull := range repoPulls[i] {
+			inMilestone[repo][pull.GetNumber()] = true
+		}
+	}
+
+	var (
+		releaseNotes []*parser.ReleaseNoteLang
+		removed      []parser.ReleaseNote
+	)
+	for _, lang := range m.Config.ReleaseNoteLanguages() {
+		langGenerated := generated
+		if lang != m.Config.PullLanguage {
+			langGenerated = generated.Placeholder(lang, path.Join(dir, fmt.Sprintf("%s-%s.md", version, lang)))
+		}
+		var releaseNote *parser.ReleaseNoteLang
+		for i := range existing {
+			if existing[i].Lang == lang {
+				releaseNote = &existing[i]
+				break
+			}
+		}
+		if releaseNote == nil {
+			releaseNotes = append(releaseNotes, langGenerated)
+			continue
+		}
+
+		// keep manual edits and translations in existing file
+		releaseNote.Merge(langGenerated)
+		langRemoved := releaseNote.FlagRemoved(func(repo types.Repo, number int) bool {
+			pulls, ok := inMilestone[repo]
+			// pulls of repos outside the product are unknown
+			return !ok || pulls[number]
+		})
+		if lang == m.Config.PullLanguage {
+			removed = langRemoved
+		}
+		releaseNotes = append(releaseNotes, releaseNote)
+	}
+	return releaseNotes, removed, nil
+}
+
+// translationCoverage reports how many notes need translation in every language
+func translationCoverage(releaseNotes []*parser.ReleaseNoteLang) string {
+	var b strings.Builder
+	for i, releaseNote := range releaseNotes {
+		untranslated, total := releaseNote.Untranslated()
+		// notes in pull-language are usually not translated
+		if i == 0 && untranslated == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %d of %d notes need translation\n", releaseNote.Lang, untranslated, total)
+	}
+	return b.String()
+}
+
+// previewReleaseNote writes rendered release note to stdout or output dir,
+// and prints the diff against the file in release note repo
+func (m *Manager) previewReleaseNote(ctx context.Context, product types.Product, releaseNote *parser.ReleaseNoteLang) error {
+	rendered, err := releaseNote.Render(product.Template)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if m.Opt.OutputDir != "" {
+		p := path.Join(m.Opt.OutputDir, releaseNote.Path)
+		if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
 			return errors.Trace(err)
 		}
+		if err := ioutil.WriteFile(p, []byte(rendered), 0644); err != nil {
+			return errors.Trace(err)
+		}
+		fmt.Printf("release note written to %s\n", p)
+	} else {
+		fmt.Println(rendered)
 	}
 
+	current, err := m.NoteCollector.GetFileContent(ctx, releaseNote.Path)
+	if err != nil {
+		if !strings.Contains(err.Error(), "404 Not Found") {
+			return errors.Trace(err)
+		}
+		current = ""
+	}
+	repoPath := strings.TrimLeft(releaseNote.Path, "/")
+	d := diff.Unified(current, rendered, "a/"+repoPath, "b/"+repoPath, 3)
+	if d == "" {
+		fmt.Printf("%s is up to date\n", repoPath)
+		return nil
+	}
+	fmt.Print(d)
+	return nil
+}
+
+// publishReleaseNote commits release note into user's fork and creates pull request
+func (m *Manager) publishReleaseNote(ctx context.Context, product types.Product, milestone *github.Milestone, releaseNotes []*parser.ReleaseNoteLang, removed []parser.ReleaseNote) error {
 	gitClient := git.New(m.Config, &git.Config{
-		Github: m.Github,
-		User:   m.User,
-		Base:   m.RelaseNoteRepo,
-		Head:   types.Repo{Owner: m.User.GetLogin(), Repo: m.RelaseNoteRepo.Repo},
-		Dir:    fmt.Sprintf("%s-%s", product.Name, milestone.GetTitle()),
+		Forge: m.Forges.For(m.RelaseNoteRepo),
+		User:  m.User,
+		Base:  m.RelaseNoteRepo,
+		Head:  types.Repo{Owner: m.User.GetLogin(), Repo: m.RelaseNoteRepo.Repo},
+		Dir:   fmt.Sprintf("%s-%s", product.Name, milestone.GetTitle()),
 	})
 	if err := gitClient.Clone(); err != nil {
 		return errors.Trace(err)
@@ -137,9 +293,14 @@ func (m *Manager) generateReleaseNoteProductMilestone(product types.Product, mil
 		}
 	}
 
-	if err := gitClient.WriteFileContent(defaultLangReleaseNote.Path,
-		defaultLangReleaseNote.String()); err != nil {
-		return errors.Trace(err)
+	for _, releaseNote := range releaseNotes {
+		rendered, err := releaseNote.Render(product.Template)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err := gitClient.WriteFileContent(releaseNote.Path, rendered); err != nil {
+			return errors.Trace(err)
+		}
 	}
 
 	commitMessage := fmt.Sprintf("update %s release notes at %s", milestone.GetTitle(), now())
@@ -152,46 +313,63 @@ func (m *Manager) generateReleaseNoteProductMilestone(product types.Product, mil
 	}
 
 	title := fmt.Sprintf("update %s %s release notes", product.Name, milestone.GetTitle())
-	if _, err := gitClient.CreatePull(title, branch); err != nil {
+	if _, err := gitClient.CreatePull(ctx, title, pullBody(title, removed, translationCoverage(releaseNotes)), branch); err != nil {
 		return errors.Trace(err)
 	}
 
 	return nil
 }
 
-func (m *Manager) makeReleaseNoteRepoMilestone(product types.Product, repo, rename types.Repo, releaseNote *parser.ReleaseNoteLang) error {
-	if releaseNote == nil {
-		return errors.New("releaseNote cannot be nil")
+// pullBody lists pulls removed from milestone and translation coverage for reviewers
+func pullBody(title string, removed []parser.ReleaseNote, coverage string) string {
+	if len(removed) == 0 && coverage == "" {
+		return title
 	}
-
-	milestone, err := m.PullCollector.GetVersionMilestone(repo, m.Opt.Version)
-	if err != nil {
-		fmt.Printf("Find milestone in %s failed", repo)
-		return nil
+	var b strings.Builder
+	b.WriteString(title)
+	if len(removed) > 0 {
+		b.WriteString("\n\nThese pull requests are removed from milestone, their release notes are kept and flagged:\n\n")
+		for _, note := range removed {
+			fmt.Fprintf(&b, "- %s#%d\n", note.Repo, note.PullNumber)
+		}
+	}
+	if coverage != "" {
+		fmt.Fprintf(&b, "\n\nNotes marked by `%s` need translation:\n\n", strings.TrimSpace(parser.TRANSLATE_FLAG))
+		for _, line := range strings.Split(strings.TrimSpace(coverage), "\n") {
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
 	}
+	return b.String()
+}
 
-	// get release notes in PR
-	_, pulls, err := m.PullCollector.ListAllMilestoneContents(repo, milestone)
-	if err != nil {
-		return errors.Trace(err)
+func (m *Manager) makeReleaseNoteRepoMilestone(ctx context.Context, product types.Product, repo, rename types.Repo, milestone *github.Milestone, pulls []*github.PullRequest, releaseNote *parser.ReleaseNoteLang) error {
+	if releaseNote == nil {
+		return errors.New("releaseNote cannot be nil")
 	}
 
-	ref := version2ref(m.Opt.Version)
+	var ref string
+	// pulls in commit range are already in the release
+	if !m.rangeMode() && len(pulls) > 0 {
+		var err error
+		if ref, err = m.Branches.Resolve(ctx, repo, milestone.GetTitle()); err != nil {
+			return errors.Trace(err)
+		}
+	}
 
 	for _, pull := range pulls {
-		if pull.GetBase().GetRef() != ref {
+		if ref != "" && pull.GetBase().GetRef() != ref {
 			continue
 		}
 		if !pull.GetMerged() {
 			continue
 		}
-		note, has := hasReleaseNote(pull.GetBody())
-		if has {
-			releaseNoteType := getReleaseNoteType(pull, product)
-			if _, ok := releaseNote.ReleaseNoteClasses[releaseNoteType]; !ok {
-				releaseNote.ReleaseNoteClasses[releaseNoteType] = make([]parser.RepoReleaseNotes, 1)
-			}
-
+		notes := pullReleaseNotes(repo, pull, m.extraction(repo).Extract(pull.GetBody(), labelNames(pull)),
+			m.Config.PullLanguage, m.Config.CollapseNotes)
+		if len(notes) == 0 {
+			continue
+		}
+		// a pull may be in several sections, like a breaking change which is also a feature
+		for _, releaseNoteType := range getReleaseNoteTypes(pull, product) {
 			var repoReleaseNote *parser.RepoReleaseNotes
 			for i := range releaseNote.ReleaseNoteClasses[releaseNoteType] {
 				if releaseNote.ReleaseNoteClasses[releaseNoteType][i].Repo.Repo == repo.Repo {
@@ -211,16 +389,10 @@ func (m *Manager) makeReleaseNoteRepoMilestone(product types.Product, repo, rena
 			for _, releaseNote := range repoReleaseNote.Notes {
 				if releaseNote.PullNumber == pull.GetNumber() {
 					inRepo = true
-					// update note
-					releaseNote.Note = note
 				}
 			}
 			if !inRepo {
-				repoReleaseNote.Notes = append(repoReleaseNote.Notes, parser.ReleaseNote{
-					Repo:       repo,
-					PullNumber: pull.GetNumber(),
-					Note:       note,
-				})
+				repoReleaseNote.Notes = append(repoReleaseNote.Notes, notes...)
 			}
 		}
 	}
@@ -228,11 +400,11 @@ func (m *Manager) makeReleaseNoteRepoMilestone(product types.Product, repo, rena
 	return nil
 }
 
-func (m *Manager) initRepo() error {
-	_, err := m.getRepo(types.Repo{Owner: m.User.GetLogin(), Repo: m.RelaseNoteRepo.Repo})
+func (m *Manager) initRepo(ctx context.Context) error {
+	_, err := m.getRepo(ctx, types.Repo{Owner: m.User.GetLogin(), Repo: m.RelaseNoteRepo.Repo})
 	if err != nil {
 		if strings.Contains(err.Error(), "Not Found") {
-			if err := m.forkRepo(m.RelaseNoteRepo); err != nil {
+			if err := m.forkRepo(ctx, m.RelaseNoteRepo); err != nil {
 				return errors.Trace(err)
 			}
 		}
@@ -240,36 +412,30 @@ func (m *Manager) initRepo() error {
 	return nil
 }
 
-func (m *Manager) getRepo(repo types.Repo) (*github.Repository, error) {
-	ctx, _ := utils.NewTimeoutContext()
-	r, _, err := m.Github.Repositories.Get(ctx, repo.Owner, repo.Repo)
+func (m *Manager) getRepo(ctx context.Context, repo types.Repo) (*github.Repository, error) {
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
+	r, err := m.Forges.For(repo).GetRepo(ctx, repo)
 	return r, errors.Trace(err)
 }
 
-func (m *Manager) forkRepo(repo types.Repo) error {
-	ctx, _ := utils.NewTimeoutContext()
-	_, _, err := m.Github.Repositories.CreateFork(ctx, repo.Owner, repo.Repo, &github.RepositoryCreateForkOptions{})
-	return errors.Trace(err)
+func (m *Manager) forkRepo(ctx context.Context, repo types.Repo) error {
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
+	return errors.Trace(m.Forges.For(repo).CreateFork(ctx, repo))
 }
 
 func now() string {
 	return time.Now().Format("2006-01-02T15:04:05")
 }
 
-func getReleaseNoteType(pull *github.PullRequest, product types.Product) string {
-	for _, label := range pull.Labels {
-		if class, ok := product.Label2Type[label.GetName()]; ok {
-			return class
-		}
+// getReleaseNoteTypes gets types of pull by labels and title, parser.OTHER_TYPE if nothing matches
+func getReleaseNoteTypes(pull *github.PullRequest, product types.Product) []string {
+	if product.Classifier == nil {
+		return []string{parser.OTHER_TYPE}
 	}
-	return "Others"
-}
-
-func version2ref(version string) string {
-	p1 := strings.Split(version, ".")[0]
-	num := numPattern.FindStringSubmatch(p1)
-	if len(num) == 2 {
-		return fmt.Sprintf("release-%s.0", num[1])
+	if classes := product.Classifier.Classify(pull.GetTitle(), labelNames(pull)); len(classes) > 0 {
+		return classes
 	}
-	return ""
+	return []string{parser.OTHER_TYPE}
 }
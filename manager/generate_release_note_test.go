@@ -0,0 +1,339 @@
+package manager
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you06/releaser/config"
+	"github.com/you06/releaser/pkg/branch"
+	"github.com/you06/releaser/pkg/extract"
+	"github.com/you06/releaser/pkg/forge"
+	"github.com/you06/releaser/pkg/githubtest"
+	"github.com/you06/releaser/pkg/parser"
+	"github.com/you06/releaser/pkg/types"
+)
+
+var (
+	testTiDB        = types.Repo{Owner: "pingcap", Repo: "tidb"}
+	testPD          = types.Repo{Owner: "pingcap", Repo: "pd"}
+	testReleaseNote = types.Repo{Owner: "pingcap", Repo: "release-note"}
+	testBot         = "releaser-bot"
+)
+
+// testEnv is a fake GitHub with local bare repos as git remotes
+type testEnv struct {
+	dir    string
+	server *githubtest.Server
+	cfg    *config.Config
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	dir, err := ioutil.TempDir("", "releaser-test")
+	require.Nil(t, err)
+	for k, v := range map[string]string{
+		"GIT_AUTHOR_NAME":     testBot,
+		"GIT_AUTHOR_EMAIL":    testBot + "@example.com",
+		"GIT_COMMITTER_NAME":  testBot,
+		"GIT_COMMITTER_EMAIL": testBot + "@example.com",
+	} {
+		t.Setenv(k, v)
+	}
+
+	server := githubtest.NewServer()
+	server.SetUser(testBot)
+	for _, repo := range []types.Repo{testTiDB, testPD, testReleaseNote} {
+		server.AddRepo(repo)
+	}
+	for _, repo := range []types.Repo{testTiDB, testPD} {
+		server.AddRef(repo, "refs/heads/master", "sha")
+		server.AddRef(repo, "refs/heads/release-4.0", "sha")
+	}
+
+	env := testEnv{
+		dir:    dir,
+		server: server,
+		cfg: &config.Config{
+			Repos:           []string{"pingcap/tidb", "pingcap/pd"},
+			ReleaseNoteRepo: "pingcap/release-note",
+			ReleaseNotePath: "/{product}",
+			PullLanguage:    "en",
+			GitDir:          path.Join(dir, "work"),
+			GraphQL:         true,
+			Products: []config.Product{
+				{
+					Name:       "tidb",
+					Repos:      []string{"pingcap/tidb", "pingcap/pd"},
+					Rename:     map[string]string{"pingcap/tidb": "PingCAP/TiDB", "pingcap/pd": "PingCAP/PD"},
+					Structure:  []string{"pingcap/tidb", "pingcap/pd"},
+					Label2Type: map[string]string{"type/bug-fix": "Bug Fixes"},
+				},
+			},
+		},
+	}
+	require.Nil(t, os.MkdirAll(env.cfg.GitDir, 0755))
+	env.initBareRepo(t, testReleaseNote)
+	env.initBareRepo(t, types.Repo{Owner: testBot, Repo: testReleaseNote.Repo})
+	return &env
+}
+
+func (e *testEnv) Close() {
+	e.server.Close()
+	os.RemoveAll(e.dir)
+}
+
+func (e *testEnv) remote(repo types.Repo) string {
+	return path.Join(e.dir, "remotes", repo.Owner, repo.Repo+".git")
+}
+
+func (e *testEnv) initBareRepo(t *testing.T, repo types.Repo) {
+	seed := path.Join(e.dir, "seed-"+repo.Owner)
+	require.Nil(t, os.MkdirAll(seed, 0755))
+	require.Nil(t, ioutil.WriteFile(path.Join(seed, "README.md"), []byte("# release notes\n"), 0644))
+	gitRun(t, seed, "init", "-q")
+	gitRun(t, seed, "checkout", "-q", "-b", "master")
+	gitRun(t, seed, "add", "README.md")
+	gitRun(t, seed, "commit", "-q", "-m", "init")
+	gitRun(t, e.dir, "clone", "-q", "--bare", seed, e.remote(repo))
+}
+
+func (e *testEnv) newManager(t *testing.T, version string) *Manager {
+	m, err := New(e.cfg, &Option{
+		Version: version,
+		APIURL:  e.server.APIURL(),
+		GitURL:  "file://" + path.Join(e.dir, "remotes"),
+	})
+	require.Nil(t, err)
+	return m
+}
+
+// addMilestone adds milestone of version to repos in pulls, and pulls into the milestone of their repos
+func (e *testEnv) addMilestone(version string, pulls map[types.Repo][]*github.PullRequest) {
+	for repo, repoPulls := range pulls {
+		milestone := e.server.AddMilestone(repo, version, "open")
+		for _, pull := range repoPulls {
+			pull.Milestone = milestone
+			e.server.AddPull(repo, pull)
+		}
+	}
+}
+
+// dryRun generates release notes of version without touching remotes, and returns the rendered file in pull-language
+func (e *testEnv) dryRun(t *testing.T, version string) (*Manager, string) {
+	m := e.newManager(t, version)
+	m.Opt.DryRun = true
+	m.Opt.OutputDir = path.Join(e.dir, "output")
+	require.Nil(t, m.Run(types.SubCmdGenerateReleaseNote))
+	content, err := ioutil.ReadFile(path.Join(m.Opt.OutputDir, "tidb", version[1:]+".md"))
+	require.Nil(t, err)
+	return m, string(content)
+}
+
+// published reads file on the update branch of version in the fork of release note repo
+func (e *testEnv) published(t *testing.T, version, file string) string {
+	return gitRun(t, e.dir, "--git-dir", e.remote(types.Repo{Owner: testBot, Repo: testReleaseNote.Repo}),
+		"show", "update-"+version[1:]+":"+file)
+}
+
+func gitRun(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.Nil(t, err, string(out))
+	return string(out)
+}
+
+func testPull(number int, milestone *github.Milestone, base, body string, labels ...string) *github.PullRequest {
+	pull := github.PullRequest{
+		Number:    github.Int(number),
+		Title:     github.String("title"),
+		Body:      github.String(body),
+		Merged:    github.Bool(true),
+		Milestone: milestone,
+		User:      &github.User{Login: github.String("author")},
+		Base:      &github.PullRequestBranch{Ref: github.String(base)},
+	}
+	for _, label := range labels {
+		pull.Labels = append(pull.Labels, &github.Label{Name: github.String(label)})
+	}
+	return &pull
+}
+
+func TestGenerateReleaseNote(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.addMilestone("v4.0.6", map[types.Repo][]*github.PullRequest{
+		testTiDB: {
+			testPull(100, nil, "release-4.0", "### Release note\n- fix a panic in executor", "type/bug-fix"),
+			testPull(101, nil, "release-4.0", "### Release note\n- No release note"),
+			testPull(102, nil, "master", "### Release note\n- master only"),
+		},
+		testPD: {testPull(200, nil, "release-4.0", "### Release note\n- support new API")},
+	})
+
+	m := env.newManager(t, "v4.0.6")
+	require.Nil(t, m.Run(types.SubCmdGenerateReleaseNote))
+
+	require.Equal(t, len(env.server.CreatedPulls), 1)
+	created := env.server.CreatedPulls[0]
+	assert.Equal(t, created.Repo, testReleaseNote)
+	assert.Equal(t, created.Pull.GetHead(), testBot+":update-4.0.6")
+	assert.Equal(t, created.Pull.GetTitle(), "update tidb v4.0.6 release notes")
+
+	content := env.published(t, "v4.0.6", "tidb/4.0.6.md")
+	assert.Contains(t, content, "## Bug Fixes\n\n+ TiDB\n\n    - Fix a panic in executor [#100](https://github.com/pingcap/tidb/pull/100)")
+	assert.Contains(t, content, "## Others\n\n+ PD\n\n    - Support new API [#200](https://github.com/pingcap/pd/pull/200)")
+	assert.NotContains(t, content, "#101")
+	assert.NotContains(t, content, "#102")
+	assert.Equal(t, env.server.CountRequests("GET /repos/pingcap/tidb/pulls/"), 0, "pulls are fetched in bulk")
+}
+
+func TestGenerateReleaseNoteDryRun(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.addMilestone("v4.0.6", map[types.Repo][]*github.PullRequest{
+		testTiDB: {testPull(100, nil, "release-4.0", "### Release note\n- fix a panic in executor", "type/bug-fix")},
+	})
+
+	_, content := env.dryRun(t, "v4.0.6")
+	assert.Equal(t, len(env.server.CreatedPulls), 0, "no pull created")
+	assert.Equal(t, len(env.server.Forks), 0, "no fork created")
+	assert.Contains(t, content, "Fix a panic in executor [#100]")
+	_, err := os.Stat(path.Join(env.cfg.GitDir, "tidb-v4.0.6"))
+	assert.True(t, os.IsNotExist(err), "nothing cloned")
+}
+
+func TestMakeReleaseNoteRepoMilestone(t *testing.T) {
+	server := githubtest.NewServer()
+	defer server.Close()
+	server.AddRepo(testTiDB)
+	server.AddRef(testTiDB, "refs/heads/release-4.0", "sha")
+	server.AddRef(testTiDB, "refs/heads/release-4.1", "sha")
+	g, err := forge.NewGitHub(server.APIURL(), "", "", "")
+	require.Nil(t, err)
+
+	unmerged := testPull(102, nil, "release-4.1", "### Release note\n- not merged")
+	unmerged.Merged = github.Bool(false)
+	pulls := []*github.PullRequest{
+		testPull(100, nil, "release-4.1", "### Release note\n- fix on 4.1"),
+		testPull(101, nil, "release-4.0", "### Release note\n- fix on 4.0"),
+		unmerged,
+	}
+	for _, c := range []struct {
+		version string
+		opt     Option
+		numbers []int
+		err     string
+	}{
+		{version: "v4.1.2", numbers: []int{100}},
+		{version: "v4.0.6", numbers: []int{101}},
+		{version: "v4.1.0-rc+build.1", numbers: []int{100}},
+		// pulls in commit range are not filtered by branch
+		{version: "v4.0.6", opt: Option{From: "v4.0.5", To: "v4.0.6"}, numbers: []int{100, 101}},
+		// a missing branch fails instead of filtering out every pull
+		{version: "v4.2.0", err: "no branch of v4.2.0 in pingcap/tidb"},
+	} {
+		opt := c.opt
+		m := Manager{
+			Config:            &config.Config{PullLanguage: "en"},
+			Opt:               &opt,
+			DefaultExtraction: extract.DefaultProfile("en"),
+			Branches:          &branch.Resolver{Forges: forge.NewSingle(g)},
+		}
+		releaseNote := parser.ReleaseNoteLang{ReleaseNoteClasses: make(map[string][]parser.RepoReleaseNotes)}
+		err := m.makeReleaseNoteRepoMilestone(context.Background(), types.Product{}, testTiDB, testTiDB,
+			&github.Milestone{Title: github.String(c.version)}, pulls, &releaseNote)
+		if c.err != "" {
+			require.NotNil(t, err, c.version)
+			assert.Contains(t, err.Error(), c.err, c.version)
+			continue
+		}
+		require.Nil(t, err, c.version)
+		var numbers []int
+		for _, note := range releaseNote.ReleaseNoteClasses[parser.OTHER_TYPE][0].Notes {
+			numbers = append(numbers, note.PullNumber)
+		}
+		assert.Equal(t, numbers, c.numbers, c.version)
+	}
+}
+
+func TestGetReleaseNoteTypes(t *testing.T) {
+	product, err := parseProducts([]config.Product{{
+		Name:       "tidb",
+		LabelRules: []config.LabelRule{{Label: "/^compatibility-/", Type: "Compatibility Changes"}},
+		Label2Type: map[string]string{"type/new-*": "New Features", "type/bug-fix": "Bug Fixes"},
+		MultiType:  true,
+	}})
+	require.Nil(t, err)
+	for _, c := range []struct {
+		title  string
+		labels []string
+		types  []string
+	}{
+		{"planner: support syntax", []string{"type/new-feature", "compatibility-breaker"}, []string{"Compatibility Changes", "New Features"}},
+		{"fix(executor): fix a panic", nil, []string{"Bug Fixes"}},
+		{"executor: refine code", []string{"component/executor"}, []string{parser.OTHER_TYPE}},
+	} {
+		pull := testPull(1, nil, "release-4.0", "", c.labels...)
+		pull.Title = github.String(c.title)
+		assert.Equal(t, getReleaseNoteTypes(pull, product[0]), c.types, c.title)
+	}
+	assert.Equal(t, getReleaseNoteTypes(testPull(1, nil, "release-4.0", ""), types.Product{}), []string{parser.OTHER_TYPE})
+}
+
+func TestPullBody(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		removed  []parser.ReleaseNote
+		coverage string
+		contains []string
+	}{
+		{"plain", nil, "", []string{"update tidb v4.0.6 release notes"}},
+		{"removed", []parser.ReleaseNote{{Repo: testTiDB, PullNumber: 99}}, "", []string{"removed from milestone", "- pingcap/tidb#99\n"}},
+		{"coverage", nil, "cn: 1 of 2 notes need translation\n", []string{"`TODO-translate:` need translation", "- cn: 1 of 2 notes need translation\n"}},
+	} {
+		body := pullBody("update tidb v4.0.6 release notes", c.removed, c.coverage)
+		for _, s := range c.contains {
+			assert.Contains(t, body, s, c.name)
+		}
+	}
+}
+
+func TestTranslationCoverage(t *testing.T) {
+	note := func(lang, text string) *parser.ReleaseNoteLang {
+		return &parser.ReleaseNoteLang{Lang: lang, ReleaseNoteClasses: map[string][]parser.RepoReleaseNotes{
+			"Bug Fixes": {{Repo: testTiDB, Notes: []parser.ReleaseNote{{Repo: testTiDB, PullNumber: 1, Note: text}}}},
+		}}
+	}
+	assert.Equal(t, translationCoverage([]*parser.ReleaseNoteLang{
+		note("en", "fix a panic"),
+		note("cn", "修复崩溃"),
+		note("jp", parser.TRANSLATE_FLAG+"Fix a panic"),
+	}), "cn: 0 of 1 notes need translation\njp: 1 of 1 notes need translation\n")
+	assert.Equal(t, translationCoverage([]*parser.ReleaseNoteLang{note("cn", parser.TRANSLATE_FLAG+"修复")}),
+		"cn: 1 of 1 notes need translation\n", "pull-language with untranslated notes is reported")
+}
+
+func TestAmbiguousMilestone(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	env.server.AddMilestone(testTiDB, "v4.0.6", "open")
+	env.server.AddMilestone(testTiDB, "4.0.6", "open")
+	m := env.newManager(t, "v4.0.6")
+	m.Opt.DryRun = true
+	m.Opt.OutputDir = path.Join(env.dir, "output")
+	for _, cmd := range []string{types.SubCmdGenerateReleaseNote, types.SubCmdCheckMilestone} {
+		err := m.Run(cmd)
+		require.NotNil(t, err, cmd)
+		assert.Contains(t, err.Error(), "milestone of v4.0.6 is ambiguous in pingcap/tidb", cmd)
+	}
+}
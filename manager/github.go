@@ -5,17 +5,16 @@ import (
 
 	"github.com/google/go-github/v30/github"
 	"github.com/juju/errors"
+	"github.com/you06/releaser/pkg/forge"
 	"github.com/you06/releaser/pkg/utils"
-	"golang.org/x/oauth2"
 )
 
 // GetReleaseNoteRepos gets repos info
-func (m *Manager) GetReleaseNoteRepos() ([]*github.Repository, error) {
+func (m *Manager) GetReleaseNoteRepos(ctx context.Context) ([]*github.Repository, error) {
 	var githubRepos []*github.Repository
 
 	for _, repo := range m.Repos {
-		ctx, _ := utils.NewTimeoutContext()
-		githubRepo, _, err := m.Github.Repositories.Get(ctx, repo.Owner, repo.Repo)
+		githubRepo, err := m.getRepo(ctx, repo)
 		if err != nil {
 			return githubRepos, errors.Trace(err)
 		}
@@ -25,18 +24,9 @@ func (m *Manager) GetReleaseNoteRepos() ([]*github.Repository, error) {
 	return githubRepos, nil
 }
 
-func initGithubClient(token string) (*github.Client, error) {
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-
-	tc := oauth2.NewClient(context.Background(), ts)
-
-	return github.NewClient(tc), nil
-}
-
-func getGithubUser(githubClient *github.Client) (*github.User, error) {
-	ctx, _ := utils.NewTimeoutContext()
-	user, _, err := githubClient.Users.Get(ctx, "")
+func getUser(ctx context.Context, f forge.Forge) (*github.User, error) {
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
+	user, err := f.GetUser(ctx)
 	return user, errors.Trace(err)
 }
@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/you06/releaser/pkg/forge"
+	"github.com/you06/releaser/pkg/utils"
+)
+
+const (
+	postComment = "comment"
+	postCheck   = "check"
+	// lintCheckName is the name of check runs posted by lint-notes
+	lintCheckName = "release-note"
+	// lintCommentFlag marks the comment posted by lint-notes, so it's edited in place on later runs
+	lintCommentFlag = "<!-- releaser: lint-notes -->"
+)
+
+func checkPostMode(mode string) error {
+	switch mode {
+	case "", postComment, postCheck:
+		return nil
+	default:
+		return errors.Errorf("invalid post mode %s", mode)
+	}
+}
+
+// postFindings posts findings back to every pull by comment or check run
+func (m *Manager) postFindings(ctx context.Context, results []pullFindings) error {
+	if m.Opt.Post == "" {
+		return nil
+	}
+	return errors.Trace(utils.Parallel(ctx, m.Config.Concurrency, len(results), func(ctx context.Context, i int) error {
+		f := m.Forges.For(results[i].Repo)
+		if m.Opt.Post == postCheck {
+			return errors.Trace(upsertLintCheck(ctx, f, results[i]))
+		}
+		return errors.Trace(upsertLintComment(ctx, f, results[i]))
+	}))
+}
+
+// withTimeout runs an API call with its own timeout
+func withTimeout(ctx context.Context, call func(ctx context.Context) error) error {
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
+	return call(ctx)
+}
+
+// upsertLintComment creates or edits the lint comment of pull, the comment is deleted if there is no finding
+func upsertLintComment(ctx context.Context, f forge.Forge, result pullFindings) error {
+	commenter, ok := f.(forge.PullCommenter)
+	if !ok {
+		return errors.Errorf("forge of %s can not comment on pulls", result.Repo)
+	}
+	number := result.Pull.GetNumber()
+	// pages are listed with their own timeouts
+	comments, err := commenter.ListPullComments(ctx, result.Repo, number)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var existing *github.IssueComment
+	for _, comment := range comments {
+		if strings.HasPrefix(comment.GetBody(), lintCommentFlag) {
+			existing = comment
+			break
+		}
+	}
+
+	if len(result.Findings) == 0 {
+		if existing == nil {
+			return nil
+		}
+		return errors.Trace(withTimeout(ctx, func(ctx context.Context) error {
+			return commenter.DeletePullComment(ctx, result.Repo, existing.GetID())
+		}))
+	}
+	body := lintCommentBody(result)
+	if existing == nil {
+		return errors.Trace(withTimeout(ctx, func(ctx context.Context) error {
+			_, err := commenter.CreatePullComment(ctx, result.Repo, number, body)
+			return err
+		}))
+	}
+	if existing.GetBody() == body {
+		return nil
+	}
+	return errors.Trace(withTimeout(ctx, func(ctx context.Context) error {
+		return commenter.EditPullComment(ctx, result.Repo, existing.GetID(), body)
+	}))
+}
+
+// upsertLintCheck creates or updates the lint check run of pull head, it passes if there is no finding
+func upsertLintCheck(ctx context.Context, f forge.Forge, result pullFindings) error {
+	runner, ok := f.(forge.CheckRunner)
+	if !ok {
+		return errors.Errorf("forge of %s can not create check runs", result.Repo)
+	}
+	sha := result.Pull.GetHead().GetSHA()
+	if sha == "" {
+		return errors.Errorf("head of %s#%d not found", result.Repo, result.Pull.GetNumber())
+	}
+	// pages are listed with their own timeouts
+	runs, err := runner.ListCheckRuns(ctx, result.Repo, sha, lintCheckName)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	var (
+		conclusion = "success"
+		output     = github.CheckRunOutput{
+			Title:   github.String("Release note looks good"),
+			Summary: github.String("No problem found in the release note."),
+		}
+		now = github.Timestamp{Time: time.Now()}
+	)
+	if len(result.Findings) > 0 {
+		conclusion = "failure"
+		output.Title = github.String(fmt.Sprintf("%d release note problems found", len(result.Findings)))
+		output.Summary = github.String(findingList(result))
+	}
+	err = withTimeout(ctx, func(ctx context.Context) error {
+		if len(runs) == 0 {
+			_, err := runner.CreateCheckRun(ctx, result.Repo, github.CreateCheckRunOptions{
+				Name:        lintCheckName,
+				HeadSHA:     sha,
+				Status:      github.String("completed"),
+				Conclusion:  github.String(conclusion),
+				CompletedAt: &now,
+				Output:      &output,
+			})
+			return err
+		}
+		return runner.UpdateCheckRun(ctx, result.Repo, runs[0].GetID(), github.UpdateCheckRunOptions{
+			Name:        lintCheckName,
+			Status:      github.String("completed"),
+			Conclusion:  github.String(conclusion),
+			CompletedAt: &now,
+			Output:      &output,
+		})
+	})
+	if isForbidden(err) {
+		return errors.Errorf("creating check runs in %s is forbidden, -post check needs github-token to be "+
+			"an installation token of a GitHub App with checks: write permission, personal access tokens are rejected: %v",
+			result.Repo, err)
+	}
+	return errors.Trace(err)
+}
+
+// isForbidden checks if err is a 403 response of GitHub API
+func isForbidden(err error) bool {
+	resp, ok := errors.Cause(err).(*github.ErrorResponse)
+	return ok && resp.Response != nil && resp.Response.StatusCode == http.StatusForbidden
+}
+
+func lintCommentBody(result pullFindings) string {
+	return fmt.Sprintf("%s\nThe release note of this pull request has problems:\n\n%s\n"+
+		"Please update the release note in the pull request description, this comment will be removed once it's fixed.\n",
+		lintCommentFlag, findingList(result))
+}
+
+func findingList(result pullFindings) string {
+	var b strings.Builder
+	for _, finding := range result.Findings {
+		fmt.Fprintf(&b, "- %s\n", finding)
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/you06/releaser/pkg/lint"
+	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
+)
+
+// pullFindings are lint findings of a pull
+type pullFindings struct {
+	Repo     types.Repo
+	Pull     *github.PullRequest
+	Findings []lint.Finding
+}
+
+func (m *Manager) runLintNotes(ctx context.Context) error {
+	if err := checkPostMode(m.Opt.Post); err != nil {
+		return errors.Trace(err)
+	}
+	var (
+		repoPulls   = make([][]*github.PullRequest, len(m.Repos))
+		noMilestone = make([]bool, len(m.Repos))
+	)
+	err := utils.Parallel(ctx, m.Config.Concurrency, len(m.Repos), func(ctx context.Context, i int) error {
+		pulls, err := m.PullCollector.ListPRList(ctx, m.Repos[i], m.Opt.Version)
+		if err != nil {
+			if strings.Contains(err.Error(), "milestone not found") {
+				noMilestone[i] = true
+				return nil
+			}
+			return errors.Trace(err)
+		}
+		repoPulls[i] = pulls
+		return nil
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	var (
+		linter  = lint.New(m.Config.Lint)
+		results []pullFindings
+	)
+	for i, repo := range m.Repos {
+		if noMilestone[i] {
+			fmt.Printf("No milestone %s in %s\n", m.Opt.Version, repo)
+			continue
+		}
+		for _, pull := range repoPulls[i] {
+			// merged and closed pulls can not be fixed before merge
+			if pull.GetState() != "open" {
+				continue
+			}
+			results = append(results, pullFindings{
+				Repo:     repo,
+				Pull:     pull,
+				Findings: linter.Lint(pull, m.pullLanguageNotes(repo, pull)),
+			})
+		}
+	}
+
+	problems, err := writeFindings(os.Stdout, results)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := m.postFindings(ctx, results); err != nil {
+		return errors.Trace(err)
+	}
+	if problems > 0 {
+		return errors.Errorf("%d release note problems found", problems)
+	}
+	return nil
+}
+
+// pullLanguageNotes gets raw bullets of release note in pull-language, or in another language if there is not,
+// the bullets are linted as written by the author
+func (m *Manager) pullLanguageNotes(repo types.Repo, pull *github.PullRequest) []string {
+	notes := m.extraction(repo).Extract(pull.GetBody(), labelNames(pull))
+	if bullets, ok := notes[m.Config.PullLanguage]; ok || len(notes) == 0 {
+		return bullets
+	}
+	var langs []string
+	for lang := range notes {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return notes[langs[0]]
+}
+
+// writeFindings prints findings of every pull, and returns the number of findings
+func writeFindings(w io.Writer, results []pullFindings) (int, error) {
+	var (
+		b        strings.Builder
+		problems int
+	)
+	for _, result := range results {
+		if len(result.Findings) == 0 {
+			continue
+		}
+		problems += len(result.Findings)
+		fmt.Fprintf(&b, "%s#%d %s\n", result.Repo, result.Pull.GetNumber(), result.Pull.GetTitle())
+		for _, finding := range result.Findings {
+			fmt.Fprintf(&b, "  %s\n", finding)
+		}
+	}
+	fmt.Fprintf(&b, "%d pulls checked, %d problems found\n", len(results), problems)
+	_, err := io.WriteString(w, b.String())
+	return problems, errors.Trace(err)
+}
@@ -0,0 +1,132 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you06/releaser/config"
+	"github.com/you06/releaser/pkg/extract"
+	"github.com/you06/releaser/pkg/lint"
+	"github.com/you06/releaser/pkg/types"
+)
+
+func TestLintNotes(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	milestone := env.server.AddMilestone(testTiDB, "v4.0.6", "open")
+	open := func(pull *github.PullRequest) *github.PullRequest {
+		pull.State = github.String("open")
+		pull.Merged = github.Bool(false)
+		return pull
+	}
+	env.server.AddPull(testTiDB, open(testPull(100, milestone, "master",
+		"### Release note\n- Fix a panic when the query is killed.", "type/bug-fix")))
+	env.server.AddPull(testTiDB, open(testPull(101, milestone, "master", "### Release note\n- No release note", "type/bug-fix")))
+	env.server.AddPull(testTiDB, open(testPull(102, milestone, "master", "### Release note\n- fix `panic")))
+	// merged pulls are not checked
+	env.server.AddPull(testTiDB, testPull(103, milestone, "master", "### Release note\n- fix", "type/bug-fix"))
+
+	m := env.newManager(t, "v4.0.6")
+	err := m.Run(types.SubCmdLintNotes)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "4 release note problems found")
+}
+
+func TestLintNotesPost(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Close()
+
+	milestone := env.server.AddMilestone(testTiDB, "v4.0.6", "open")
+	pull := testPull(100, milestone, "master", "### Release note\n- fix `panic", "type/bug-fix")
+	pull.State = github.String("open")
+	pull.Merged = github.Bool(false)
+	pull.Head = &github.PullRequestBranch{SHA: github.String("abc")}
+	env.server.AddPull(testTiDB, pull)
+
+	m := env.newManager(t, "v4.0.6")
+	m.Opt.Post = postComment
+	require.NotNil(t, m.Run(types.SubCmdLintNotes))
+	comments := env.server.PullComments(testTiDB, 100)
+	require.Equal(t, len(comments), 1)
+	assert.Contains(t, comments[0].GetBody(), lintCommentFlag)
+	assert.Contains(t, comments[0].GetBody(), "- [backtick] backticks are not balanced: fix `panic")
+
+	// the comment is edited in place
+	pull.Body = github.String("### Release note\n- Fix a panic when `SELECT` is killed")
+	require.NotNil(t, m.Run(types.SubCmdLintNotes))
+	comments = env.server.PullComments(testTiDB, 100)
+	require.Equal(t, len(comments), 1)
+	assert.Contains(t, comments[0].GetBody(), "[trailing-period]")
+	assert.NotContains(t, comments[0].GetBody(), "[backtick]")
+
+	m.Opt.Post = postCheck
+	require.NotNil(t, m.Run(types.SubCmdLintNotes))
+	runs := env.server.CheckRuns(testTiDB, "abc")
+	require.Equal(t, len(runs), 1)
+	assert.Equal(t, runs[0].GetConclusion(), "failure")
+
+	// the comment is deleted and the check passes once the note is fixed
+	pull.Body = github.String("### Release note\n- Fix a panic when `SELECT` is killed.")
+	require.Nil(t, m.Run(types.SubCmdLintNotes))
+	runs = env.server.CheckRuns(testTiDB, "abc")
+	require.Equal(t, len(runs), 1)
+	assert.Equal(t, runs[0].GetConclusion(), "success")
+	m.Opt.Post = postComment
+	require.Nil(t, m.Run(types.SubCmdLintNotes))
+	assert.Equal(t, len(env.server.PullComments(testTiDB, 100)), 0)
+
+	// check runs can not be written with a personal access token
+	env.server.ForbidCheckRuns = true
+	m.Opt.Post = postCheck
+	err := m.Run(types.SubCmdLintNotes)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "installation token of a GitHub App")
+
+	m.Opt.Post = "email"
+	assert.NotNil(t, m.Run(types.SubCmdLintNotes))
+}
+
+func TestWriteFindings(t *testing.T) {
+	var b strings.Builder
+	problems, err := writeFindings(&b, []pullFindings{
+		{Repo: testTiDB, Pull: testPull(100, nil, "master", "")},
+		{Repo: testTiDB, Pull: testPull(101, nil, "master", ""), Findings: []lint.Finding{
+			{Rule: lint.RuleMissingNote, Message: "pull with label type/bug-fix should have release note"},
+		}},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, problems, 1)
+	assert.Equal(t, b.String(), `pingcap/tidb#101 title
+  [missing-note] pull with label type/bug-fix should have release note
+2 pulls checked, 1 problems found
+`)
+}
+
+func TestPullLanguageNotes(t *testing.T) {
+	profile, err := extract.NewProfile(config.Extraction{Headings: map[string]string{"en": "release note", "cn": "发布说明"}}, "en")
+	require.Nil(t, err)
+	m := Manager{Config: &config.Config{PullLanguage: "en"}, DefaultExtraction: profile}
+	for _, c := range []struct {
+		body  string
+		notes []string
+	}{
+		{"### Release note\n- fix a panic.\n### 发布说明\n- 修复崩溃。", []string{"fix a panic."}},
+		// bullets in another language are linted as written, not capitalized like generated notes
+		{"### 发布说明\n- fix a panic.", []string{"fix a panic."}},
+		{"no release note section", nil},
+	} {
+		pull := testPull(1, nil, "master", c.body)
+		notes := m.pullLanguageNotes(testTiDB, pull)
+		assert.Equal(t, notes, c.notes, c.body)
+		if len(notes) == 0 {
+			continue
+		}
+		findings := lint.New(config.Lint{}).Lint(pull, notes)
+		require.Equal(t, len(findings), 1, c.body)
+		assert.Equal(t, findings[0].Rule, lint.RuleLowercase, c.body)
+	}
+}
@@ -1,15 +1,24 @@
 package manager
 
 import (
+	"context"
+	"io/ioutil"
+	"path"
 	"regexp"
 	"strings"
+	"text/template"
 
 	"github.com/google/go-github/v30/github"
 	"github.com/juju/errors"
 	"github.com/nlopes/slack"
 	"github.com/you06/releaser/config"
+	"github.com/you06/releaser/pkg/branch"
+	"github.com/you06/releaser/pkg/classify"
 	"github.com/you06/releaser/pkg/dependency"
+	"github.com/you06/releaser/pkg/extract"
+	"github.com/you06/releaser/pkg/forge"
 	"github.com/you06/releaser/pkg/note"
+	"github.com/you06/releaser/pkg/parser"
 	"github.com/you06/releaser/pkg/pull"
 	"github.com/you06/releaser/pkg/types"
 )
@@ -25,9 +34,14 @@ type Manager struct {
 	User     *github.User
 	Repos    []types.Repo
 	Products []types.Product
+	// Extractions are rules to extract release notes of repos, repos not in it use DefaultExtraction
+	Extractions       map[types.Repo]*extract.Profile
+	DefaultExtraction *extract.Profile
+	// Branches resolves release branches of versions
+	Branches *branch.Resolver
 
 	RelaseNoteRepo      types.Repo
-	Github              *github.Client
+	Forges              *forge.Registry
 	Slack               *slack.Client
 	NoteCollector       *note.Collector
 	PullCollector       *pull.Collector
@@ -37,6 +51,24 @@ type Manager struct {
 // Option for usage
 type Option struct {
 	Version string
+	Format  string
+	// DryRun renders release notes without touching any remote
+	DryRun bool
+	// OutputDir is where dry run writes rendered release notes, empty means stdout
+	OutputDir string
+	// APIURL overrides the GitHub API base url, empty means api.github.com
+	APIURL string
+	// GitURL overrides the git remote of GitHub repos, empty means https://github.com
+	GitURL string
+	// NoCache disables the on-disk cache of API responses
+	NoCache bool
+	// From and To select pulls by commits between two refs instead of milestones
+	From string
+	To   string
+	// Fix makes check-milestone fix milestones of pulls
+	Fix bool
+	// Post is how lint-notes posts findings back to pulls, "comment", "check" or empty for not posting
+	Post string
 }
 
 // New create releaser manager
@@ -49,38 +81,68 @@ func New(cfg *config.Config, opt *Option) (*Manager, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	relaseNoteRepo, err := parseRepo(cfg.ReleaseNoteRepo)
+	extractions, defaultExtraction, err := parseExtractions(cfg.Extractions, cfg.PullLanguage)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	githubClient, err := initGithubClient(cfg.GithubToken)
+	branchTemplates, defaultBranchTemplates, err := parseBranches(cfg.Branches)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	user, err := getGithubUser(githubClient)
+	relaseNoteRepo, err := types.ParseRepo(cfg.ReleaseNoteRepo)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	cacheDir := cfg.CachePath()
+	if opt.NoCache {
+		cacheDir = ""
+	}
+	forges, err := forge.New(cfg, cacheDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if opt.APIURL != "" || opt.GitURL != "" {
+		forges.Default, err = forge.NewGitHub(opt.APIURL, opt.GitURL, cfg.GithubToken, cacheDir)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	releaseNoteForge := forges.For(relaseNoteRepo)
+	user, err := getUser(context.Background(), releaseNoteForge)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 
 	m := Manager{
-		Config:         cfg,
-		Opt:            opt,
-		Repos:          repos,
-		Products:       products,
+		Config:            cfg,
+		Opt:               opt,
+		Repos:             repos,
+		Products:          products,
+		Extractions:       extractions,
+		DefaultExtraction: defaultExtraction,
+		Branches: &branch.Resolver{
+			Forges:    forges,
+			Templates: branchTemplates,
+			Default:   defaultBranchTemplates,
+		},
 		RelaseNoteRepo: relaseNoteRepo,
-		Github:         githubClient,
+		Forges:         forges,
 		User:           user,
 		Slack:          initSlackClient(cfg.SlackToken),
-		NoteCollector:  note.New(githubClient, cfg, relaseNoteRepo),
-		PullCollector:  pull.New(githubClient, cfg),
+		NoteCollector:  note.New(releaseNoteForge, cfg, relaseNoteRepo),
+		PullCollector:  pull.New(forges, cfg),
 		DependencyCollector: dependency.New(&dependency.Config{
 			Config: cfg,
-			Github: githubClient,
+			Forges: forges,
 			User:   user,
 		}),
 	}
 
-	if _, err := m.GetReleaseNoteRepos(); err != nil {
+	m.PullCollector.HasNote = func(repo types.Repo, pull *github.PullRequest) bool {
+		return len(m.extraction(repo).Extract(pull.GetBody(), labelNames(pull))) > 0
+	}
+
+	if _, err := m.GetReleaseNoteRepos(context.Background()); err != nil {
 		return nil, errors.Trace(err)
 	}
 
@@ -89,15 +151,23 @@ func New(cfg *config.Config, opt *Option) (*Manager, error) {
 
 // Run start sub commands
 func (m *Manager) Run(subCommand string) error {
+	// all API calls share the context, so the remaining work is canceled on the first fatal error
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	switch subCommand {
 	case types.SubCmdPRList:
-		return errors.Trace(m.runRRList())
+		return errors.Trace(m.runRRList(ctx))
 	case types.SubCmdReleaseNotes:
-		return errors.Trace(m.runReleaseNotes())
+		return errors.Trace(m.runReleaseNotes(ctx))
 	case types.SubCmdGenerateReleaseNote:
-		return errors.Trace(m.runGenerateReleaseNote())
+		return errors.Trace(m.runGenerateReleaseNote(ctx))
 	case types.SubCmdCheckModule:
-		return errors.Trace(m.runCheckModule())
+		return errors.Trace(m.runCheckModule(ctx))
+	case types.SubCmdLintNotes:
+		return errors.Trace(m.runLintNotes(ctx))
+	case types.SubCmdCheckMilestone:
+		return errors.Trace(m.runCheckMilestone(ctx))
 	default:
 		return errors.New("invalid sub command")
 	}
@@ -119,12 +189,30 @@ func parseProducts(products []config.Product) ([]types.Product, error) {
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		tmpl, err := parseTemplate(product.Template)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := parser.CheckNoteOrder(product.NoteOrder); err != nil {
+			return nil, errors.Trace(err)
+		}
+		typeOrder := product.TypeOrder
+		if len(typeOrder) == 0 {
+			typeOrder = parser.DEFAULT_TYPE_ORDER
+		}
+		classifier, err := classify.New(product, typeOrder)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
 		p = append(p, types.Product{
 			Name:       product.Name,
 			Repos:      repos,
 			Renames:    renames,
 			Structure:  structure,
-			Label2Type: product.Label2Type,
+			Classifier: classifier,
+			TypeOrder:  product.TypeOrder,
+			NoteOrder:  product.NoteOrder,
+			Template:   tmpl,
 		})
 	}
 
@@ -137,7 +225,7 @@ func parseRepos(repoStrs []string) ([]types.Repo, error) {
 	)
 
 	for _, repoStr := range repoStrs {
-		repo, err := parseRepo(repoStr)
+		repo, err := types.ParseRepo(repoStr)
 		if err != nil {
 			return repos, errors.Trace(err)
 		}
@@ -150,11 +238,11 @@ func parseRepos(repoStrs []string) ([]types.Repo, error) {
 func parseRenames(renameSources map[string]string) (map[types.Repo]types.Repo, error) {
 	renameRepo := make(map[types.Repo]types.Repo)
 	for k, v := range renameSources {
-		kRepo, err := parseRepo(k)
+		kRepo, err := types.ParseRepo(k)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
-		vRepo, err := parseRepo(v)
+		vRepo, err := types.ParseRepo(v)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -163,18 +251,68 @@ func parseRenames(renameSources map[string]string) (map[types.Repo]types.Repo, e
 	return renameRepo, nil
 }
 
-func parseRepo(repo string) (types.Repo, error) {
+// parseExtractions parses rules to extract release notes of repos, and the default rule which has no repos
+func parseExtractions(extractions []config.Extraction, lang string) (map[types.Repo]*extract.Profile, *extract.Profile, error) {
 	var (
-		p = strings.Split(repo, "/")
-		r types.Repo
+		res               = make(map[types.Repo]*extract.Profile)
+		defaultExtraction = extract.DefaultProfile(lang)
 	)
-	if len(p) != 2 {
-		return r, errors.Errorf("repo %s not valid", repo)
+	for _, extraction := range extractions {
+		profile, err := extract.NewProfile(extraction, lang)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		if len(extraction.Repos) == 0 {
+			defaultExtraction = profile
+			continue
+		}
+		repos, err := parseRepos(extraction.Repos)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		for _, repo := range repos {
+			res[repo] = profile
+		}
 	}
+	return res, defaultExtraction, nil
+}
 
-	r.Owner, r.Repo = p[0], p[1]
+// parseBranches parses branch templates of repos, and the default templates which have no repos
+func parseBranches(branches []config.Branch) (map[types.Repo][]string, []string, error) {
+	var (
+		res              = make(map[types.Repo][]string)
+		defaultTemplates = branch.DefaultTemplates
+	)
+	for _, b := range branches {
+		if len(b.Templates) == 0 {
+			return nil, nil, errors.Errorf("branch of %v has no templates", b.Repos)
+		}
+		if len(b.Repos) == 0 {
+			defaultTemplates = b.Templates
+			continue
+		}
+		repos, err := parseRepos(b.Repos)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		for _, repo := range repos {
+			res[repo] = b.Templates
+		}
+	}
+	return res, defaultTemplates, nil
+}
 
-	return r, nil
+// parseTemplate reads release note template from file, nil means the default template
+func parseTemplate(file string) (*template.Template, error) {
+	if file == "" {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tmpl, err := parser.NewTemplate(path.Base(file), string(content))
+	return tmpl, errors.Trace(err)
 }
 
 func parseStructure(structure []string) ([]types.ProductItem, error) {
@@ -182,7 +320,7 @@ func parseStructure(structure []string) ([]types.ProductItem, error) {
 	for _, item := range structure {
 		structureMatch := structurePattern.FindStringSubmatch(item)
 		if len(structureMatch) != 3 {
-			repo, err := parseRepo(item)
+			repo, err := types.ParseRepo(item)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
@@ -195,7 +333,7 @@ func parseStructure(structure []string) ([]types.ProductItem, error) {
 		var children []types.ProductItem
 		for _, repoRaw := range strings.Split(repoRaws, ",") {
 			repoRaw = strings.Trim(repoRaw, " ")
-			repo, err := parseRepo(repoRaw)
+			repo, err := types.ParseRepo(repoRaw)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
@@ -1,31 +1,48 @@
 package manager
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
+	"github.com/google/go-github/v30/github"
 	"github.com/juju/errors"
 	"github.com/olekukonko/tablewriter"
 	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
 )
 
-func (m *Manager) runRRList() error {
+func (m *Manager) runRRList(ctx context.Context) error {
 	var (
 		tableString      = strings.Builder{}
 		table            = tablewriter.NewWriter(&tableString)
 		noMilestoneRepos types.Repos
+		repoPulls        = make([][]*github.PullRequest, len(m.Repos))
+		noMilestone      = make([]bool, len(m.Repos))
 	)
-	table.SetHeader([]string{"Repo", "PR", "Author", "Title"})
-	for _, repo := range m.Repos {
-		pulls, err := m.PullCollector.ListPRList(repo, m.Opt.Version)
+	err := utils.Parallel(ctx, m.Config.Concurrency, len(m.Repos), func(ctx context.Context, i int) error {
+		pulls, err := m.PullCollector.ListPRList(ctx, m.Repos[i], m.Opt.Version)
 		if err != nil {
 			if strings.Contains(err.Error(), "milestone not found") {
-				noMilestoneRepos = append(noMilestoneRepos, repo)
-				continue
+				noMilestone[i] = true
+				return nil
 			}
 			return errors.Trace(err)
 		}
-		for _, pull := range pulls {
+		repoPulls[i] = pulls
+		return nil
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	table.SetHeader([]string{"Repo", "PR", "Author", "Title"})
+	for i, repo := range m.Repos {
+		if noMilestone[i] {
+			noMilestoneRepos = append(noMilestoneRepos, repo)
+			continue
+		}
+		for _, pull := range repoPulls[i] {
 			var (
 				repo    = repo.String()
 				pullStr = fmt.Sprintf("%d", pull.GetNumber())
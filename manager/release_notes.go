@@ -1,8 +1,18 @@
 package manager
 
 import (
-	"regexp"
+	"context"
+	"fmt"
+	"sort"
 	"strings"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/olekukonko/tablewriter"
+	"github.com/you06/releaser/pkg/extract"
+	"github.com/you06/releaser/pkg/parser"
+	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
 )
 
 const (
@@ -12,169 +22,232 @@ const (
 
 var shouldExistLangs = []string{"cn", "en"}
 
-var (
-	commentPattern       = regexp.MustCompile(`<!--[^>]*-->`)
-	releaseNoteStart     = regexp.MustCompile(`^.*release ?note.*$`)
-	releaseNoteListMatch = regexp.MustCompile(`^- ?(.*)$`)
-	releaseNoteNAMatch   = regexp.MustCompile(`^\s*` + "`?" + `\(?(no release note|na\.?|no need\.?|none\.?|no\.?|no. it's trivial.|n\/a)\)?` + "`?" + `\.?\s*$`)
-	titlePattern         = regexp.MustCompile(`^\#{1,3}\ .*$`)
-)
+func (m *Manager) runReleaseNotes(ctx context.Context) error {
+	for _, product := range m.Products {
+		if err := m.releaseNotesProduct(ctx, product); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) releaseNotesProduct(ctx context.Context, product types.Product) error {
+	// Do not process empty product
+	if len(product.Repos) == 0 {
+		return nil
+	}
+
+	milestones, err := m.productMilestones(ctx, product)
+	if err != nil {
+		return errors.Trace(err)
+	}
 
-func (m *Manager) runReleaseNotes() error {
+	for _, milestone := range milestones {
+		if err := m.releaseNotesProductMilestone(ctx, product, milestone); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	return nil
 }
 
-// func (m *Manager) runReleaseNotes() error {
-// 	var errs []error
-// 	for _, product := range m.Products {
-// 		pulls, err := m.PullCollector.ListPRList(repo, m.Opt.Version)
-// 		if err != nil {
-// 			if strings.Contains(err.Error(), "milestone not found") {
-// 				fmt.Printf("can not find milestone %s in %s\n", m.Opt.Version, repo.String())
-// 				continue
-// 			}
-// 			return errors.Trace(err)
-// 		}
-// 		releaseNotes, err := m.NoteCollector.ListReleaseNote(product, m.Opt.Version)
-// 		if err != nil {
-// 			fmt.Printf("get release notes error %+v\n", err)
-// 		}
-// 		var (
-// 			langs            []string
-// 			tableString      strings.Builder
-// 			table            = tablewriter.NewWriter(&tableString)
-// 			slackTableString strings.Builder
-// 			slackTable       = tablewriter.NewWriter(&slackTableString)
-// 			rows             [][]string
-// 		)
-// 		for _, releaseNote := range releaseNotes {
-// 			langs = append(langs, releaseNote.Lang)
-// 		}
-// 		table.SetHeader(append([]string{"Repo", "PR", "Author", "Title", "Release Note"}, langs...))
-// 		slackTable.SetHeader(append([]string{"Repo", "PR", "Author", "Title", "Release Note"}, langs...))
-// 		for _, pull := range pulls {
-// 			var (
-// 				repo                  = repo.String()
-// 				pullStr               = fmt.Sprintf("%d", pull.GetNumber())
-// 				author                = pull.GetUser().GetLogin()
-// 				title                 = pull.GetTitle()
-// 				langStatus            []string
-// 				_, pullHasReleaseNote = hasReleaseNote(pull.GetBody())
-// 			)
-// 			if pullHasReleaseNote {
-// 				langStatus = append(langStatus, iconHasNote)
-// 			} else {
-// 				langStatus = append(langStatus, iconNoNote)
-// 			}
-// 			for _, releaseNote := range releaseNotes {
-// 				hasNote := false
-// 				for _, note := range releaseNote.Notes {
-// 					if note.PullNumber == pull.GetNumber() {
-// 						hasNote = true
-// 					}
-// 				}
-// 				if hasNote {
-// 					langStatus = append(langStatus, iconHasNote)
-// 				} else {
-// 					langStatus = append(langStatus, iconNoNote)
-// 				}
-// 			}
-// 			rows = append(rows, (append([]string{repo, pullStr, author, title}, langStatus...)))
-// 		}
-
-// 		for _, row := range rows {
-// 			table.Append(row)
-// 			allOk := true
-// 			for i := 0; i < len(langs); i++ {
-// 				if row[len(row)-i-1] == iconNoNote {
-// 					allOk = false
-// 				}
-// 			}
-// 			if !allOk && row[len(row)-len(langs)-1] == iconHasNote {
-// 				slackTable.Append(row)
-// 			}
-// 		}
-
-// 		table.Render()
-// 		slackTable.Render()
-
-// 		if len(langs) != 2 {
-// 			slackTableString.Reset()
-// 			var missingLangs []string
-// 			for _, lang := range shouldExistLangs {
-// 				has := false
-// 				for _, existLang := range langs {
-// 					if lang == existLang {
-// 						has = true
-// 					}
-// 				}
-// 				if !has {
-// 					missingLangs = append(missingLangs, lang)
-// 				}
-// 			}
-// 			existLangs := strings.Join(langs, ", ")
-// 			if len(langs) == 0 {
-// 				existLangs = "None"
-// 			}
-// 			fmt.Fprintf(&slackTableString, "Found language: %s. Missing: %s.", existLangs, strings.Join(missingLangs, ", "))
-// 		}
-
-// 		fmt.Println(tableString.String())
-// 		errs = append(errs, m.SendMessage(fmt.Sprintf("```%s Version:%s\n%s```", repo.String(), m.Opt.Version, slackTableString.String())))
-// 	}
-
-// 	for _, err := range errs {
-// 		if err != nil {
-// 			return errors.Trace(err)
-// 		}
-// 	}
-// 	return nil
-// }
-
-func hasReleaseNote(body string) (string, bool) {
+func (m *Manager) releaseNotesProductMilestone(ctx context.Context, product types.Product, milestone *github.Milestone) error {
+	version := milestone.GetTitle()
+	releaseNotes, err := m.NoteCollector.ListReleaseNote(ctx, product, version)
+	if err != nil {
+		fmt.Printf("get release notes error %+v\n", err)
+	}
+
+	var (
+		langs            []string
+		tableString      strings.Builder
+		table            = tablewriter.NewWriter(&tableString)
+		slackTableString strings.Builder
+		slackTable       = tablewriter.NewWriter(&slackTableString)
+		noMilestoneRepos types.Repos
+		repoPulls        = make([][]*github.PullRequest, len(product.Repos))
+		noMilestone      = make([]bool, len(product.Repos))
+	)
+	for _, releaseNote := range releaseNotes {
+		langs = append(langs, releaseNote.Lang)
+	}
+	header := append([]string{"Repo", "PR", "Author", "Title", "Release Note"}, langs...)
+	table.SetHeader(header)
+	slackTable.SetHeader(header)
+
+	err = utils.Parallel(ctx, m.Config.Concurrency, len(product.Repos), func(ctx context.Context, i int) error {
+		pulls, err := m.PullCollector.ListPRList(ctx, product.Repos[i], version)
+		if err != nil {
+			if strings.Contains(err.Error(), "milestone not found") {
+				noMilestone[i] = true
+				return nil
+			}
+			return errors.Trace(err)
+		}
+		repoPulls[i] = pulls
+		return nil
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	for i, repo := range product.Repos {
+		if noMilestone[i] {
+			noMilestoneRepos = append(noMilestoneRepos, repo)
+			continue
+		}
+		for _, pull := range repoPulls[i] {
+			row := releaseNoteAuditRow(repo, pull, releaseNotes, m.extraction(repo))
+			table.Append(row)
+			// only remind those PRs which have a note but missing in some language files
+			if row[4] == iconHasNote && !allHasNote(row[5:]) {
+				slackTable.Append(row)
+			}
+		}
+	}
+
+	table.Render()
+	slackTable.Render()
+
+	if missingLangs := findMissingLangs(langs); len(missingLangs) > 0 {
+		slackTableString.Reset()
+		existLangs := strings.Join(langs, ", ")
+		if len(langs) == 0 {
+			existLangs = "None"
+		}
+		fmt.Fprintf(&slackTableString, "Found language: %s. Missing: %s.",
+			existLangs, strings.Join(missingLangs, ", "))
+	}
+
+	fmt.Printf("%s %s\n", product.Name, version)
+	fmt.Println(tableString.String())
+	if len(noMilestoneRepos) > 0 {
+		fmt.Printf("No milestone repos: %s\n", noMilestoneRepos)
+	}
+
+	return errors.Trace(m.SendMessage(fmt.Sprintf("```%s Version:%s\n%s```",
+		product.Name, version, slackTableString.String())))
+}
+
+func releaseNoteAuditRow(repo types.Repo, pull *github.PullRequest, releaseNotes []parser.ReleaseNoteLang, extraction *extract.Profile) []string {
 	var (
-		findReleaseNoteStart = false
-		releaseNoteLines     []string
+		pullStr            = fmt.Sprintf("%d", pull.GetNumber())
+		author             = pull.GetUser().GetLogin()
+		title              = pull.GetTitle()
+		langStatus         []string
+		pullHasReleaseNote = len(extraction.Extract(pull.GetBody(), labelNames(pull))) > 0
 	)
+	langStatus = append(langStatus, noteIcon(pullHasReleaseNote))
+	for _, releaseNote := range releaseNotes {
+		langStatus = append(langStatus, noteIcon(releaseNote.HasPull(repo, pull.GetNumber())))
+	}
+	return append([]string{repo.String(), pullStr, author, title}, langStatus...)
+}
 
-	body = commentPattern.ReplaceAllString(body, "")
-	body = strings.ReplaceAll(body, "\r", "")
+func noteIcon(has bool) string {
+	if has {
+		return iconHasNote
+	}
+	return iconNoNote
+}
 
-	for _, line := range strings.Split(body, "\n") {
-		line = strings.Trim(line, " ")
-		if findReleaseNoteStart {
-			releaseNoteLines = append(releaseNoteLines, strings.Trim(line, " "))
+func allHasNote(status []string) bool {
+	for _, s := range status {
+		if s != iconHasNote {
+			return false
+		}
+	}
+	return true
+}
+
+func findMissingLangs(langs []string) []string {
+	var missingLangs []string
+	for _, lang := range shouldExistLangs {
+		has := false
+		for _, existLang := range langs {
+			if lang == existLang {
+				has = true
+			}
+		}
+		if !has {
+			missingLangs = append(missingLangs, lang)
 		}
-		if releaseNoteStart.MatchString(strings.ToLower(line)) {
-			findReleaseNoteStart = true
+	}
+	return missingLangs
+}
+
+// extraction returns the rule to extract release notes from pull requests of repo
+func (m *Manager) extraction(repo types.Repo) *extract.Profile {
+	if profile, ok := m.Extractions[repo]; ok {
+		return profile
+	}
+	return m.DefaultExtraction
+}
+
+// pullReleaseNotes makes a note of every bullet in pull-language, or in another language marked to be translated,
+// bullets in other languages are their translations. Bullets are collapsed into one note if collapse is set.
+func pullReleaseNotes(repo types.Repo, pull *github.PullRequest, notes map[string][]string, pullLanguage string, collapse bool) []parser.ReleaseNote {
+	if len(notes) == 0 {
+		return nil
+	}
+	if collapse {
+		// notes of caller are kept as they are
+		collapsed := make(map[string][]string, len(notes))
+		for lang, bullets := range notes {
+			collapsed[lang] = []string{collapseBullets(bullets)}
 		}
+		notes = collapsed
 	}
-	for _, line := range releaseNoteLines {
-		line = removeHeader(line)
-		listMatch := releaseNoteListMatch.FindStringSubmatch(line)
-		if len(listMatch) == 2 {
-			line = listMatch[1]
+
+	source, ok := notes[pullLanguage]
+	if !ok {
+		var langs []string
+		for lang := range notes {
+			langs = append(langs, lang)
+		}
+		sort.Strings(langs)
+		for _, bullet := range notes[langs[0]] {
+			source = append(source, parser.TRANSLATE_FLAG+parser.Ucfirst(bullet))
 		}
-		if releaseNoteNAMatch.MatchString(strings.ToLower(line)) {
-			return "", false
+	}
+
+	var res []parser.ReleaseNote
+	for i, bullet := range source {
+		note := parser.ReleaseNote{
+			Repo:       repo,
+			PullNumber: pull.GetNumber(),
+			Note:       bullet,
+			Author:     pull.GetUser().GetLogin(),
+			MergedAt:   pull.GetMergedAt(),
 		}
-		if titlePattern.MatchString(strings.ToLower(line)) {
-			return "", false
+		for lang, bullets := range notes {
+			// translations are matched by order
+			if lang == pullLanguage || i >= len(bullets) {
+				continue
+			}
+			if note.Translations == nil {
+				note.Translations = make(map[string]string)
+			}
+			note.Translations[lang] = bullets[i]
 		}
-		if line != "" {
-			return line, true
+		res = append(res, note)
+	}
+	return res
+}
+
+// collapseBullets joins bullets by "; ", multi-line bullets are separated by blank lines
+func collapseBullets(bullets []string) string {
+	for _, bullet := range bullets {
+		if strings.Contains(bullet, "\n") {
+			return strings.Join(bullets, "\n\n")
 		}
 	}
-	return "", false
+	return strings.Join(bullets, "; ")
 }
 
-func removeHeader(line string) string {
-	origin := line
-	line = strings.Trim(line, " ")
-	line = strings.TrimLeft(line, "*")
-	line = strings.TrimLeft(line, "-")
-	if line == origin {
-		return line
+func labelNames(pull *github.PullRequest) []string {
+	var names []string
+	for _, label := range pull.Labels {
+		names = append(names, label.GetName())
 	}
-	return removeHeader(line)
+	return names
 }
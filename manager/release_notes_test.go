@@ -4,24 +4,27 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-type hasReleaseNoteRes struct {
-	note string
-	has  bool
+func TestPullReleaseNotes(t *testing.T) {
+	pull := testPull(1, nil, "master", "")
+	notes := pullReleaseNotes(testTiDB, pull, map[string][]string{"cn": {"修复", "支持"}, "jp": {"修正"}}, "en", false)
+	require.Equal(t, len(notes), 2)
+	assert.Equal(t, notes[0].Note, "TODO-translate: 修复")
+	assert.Equal(t, notes[0].Translations, map[string]string{"cn": "修复", "jp": "修正"})
+	assert.Equal(t, notes[1].Translations, map[string]string{"cn": "支持"})
+	extracted := map[string][]string{"en": {"fix", "support"}}
+	notes = pullReleaseNotes(testTiDB, pull, extracted, "en", true)
+	require.Equal(t, len(notes), 1)
+	assert.Equal(t, notes[0].Note, "fix; support")
+	assert.Equal(t, extracted, map[string][]string{"en": {"fix", "support"}}, "bullets of caller are not collapsed")
+	assert.Equal(t, len(pullReleaseNotes(testTiDB, pull, nil, "en", false)), 0)
+	assert.Equal(t, collapseBullets([]string{"a", "b\nc"}), "a\n\nb\nc")
 }
 
-func testHasReleaseNote(raw string) hasReleaseNoteRes {
-	note, has := hasReleaseNote(raw)
-	return hasReleaseNoteRes{note, has}
-}
-
-func TestHasReleaseNote(t *testing.T) {
-	assert.Equal(t, testHasReleaseNote("Bug Fix \nRelease note\n- NA"), hasReleaseNoteRes{"", false}, "case 1")
-	assert.Equal(t, testHasReleaseNote("Bug Fix \nRelease note\n- N/A"), hasReleaseNoteRes{"", false}, "case 2")
-	assert.Equal(t, testHasReleaseNote("Bug Fix \nRelease note\n- (N/A)"), hasReleaseNoteRes{"", false}, "case 3")
-	assert.Equal(t, testHasReleaseNote("### Release note <!-- bugfixes or new feature need a release note -->\n\n* No release note"), hasReleaseNoteRes{"", false}, "case 4")
-	assert.Equal(t, testHasReleaseNote("### Release note <!-- bugfixes or new feature need a release note -->\n- No release note."), hasReleaseNoteRes{"", false}, "case 5")
-	assert.Equal(t, testHasReleaseNote("### Release note <!-- bugfixes or new feature need a release note -->\n- `No release note`"), hasReleaseNoteRes{"", false}, "case 6")
-	assert.Equal(t, testHasReleaseNote("### Release note <!-- bugfixes or new feature need a release note -->\n- `No release note`."), hasReleaseNoteRes{"", false}, "case 6")
+func TestFindMissingLangs(t *testing.T) {
+	assert.Equal(t, findMissingLangs([]string{"cn", "en"}), []string(nil), "all exist")
+	assert.Equal(t, findMissingLangs([]string{"en"}), []string{"cn"}, "cn missing")
+	assert.Equal(t, findMissingLangs(nil), []string{"cn", "en"}, "all missing")
 }
@@ -0,0 +1,123 @@
+package branch
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/you06/releaser/pkg/forge"
+	"github.com/you06/releaser/pkg/semver"
+	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
+)
+
+var (
+	// DefaultTemplates are branch templates of repos which are not configured
+	DefaultTemplates = []string{"release-{major}.{minor}"}
+
+	// versionPattern finds the version in milestone titles like "TiDB v4.0.6"
+	versionPattern = regexp.MustCompile(`v?\d+(\.\d+){0,2}(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?`)
+)
+
+// Expand fills placeholders {major}, {minor}, {patch}, {pre-release} and {version} of template,
+// {version} is the version without leading "v" and build metadata
+func Expand(template string, v semver.Version) string {
+	version := strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor) + "." + strconv.Itoa(v.Patch)
+	pre := strings.Join(v.PreRelease, ".")
+	if pre != "" {
+		version += "-" + pre
+	}
+	return strings.NewReplacer(
+		"{major}", strconv.Itoa(v.Major),
+		"{minor}", strconv.Itoa(v.Minor),
+		"{patch}", strconv.Itoa(v.Patch),
+		"{pre-release}", pre,
+		"{version}", version,
+	).Replace(template)
+}
+
+// ParseVersion parses version, or the first version in it if it's a title like "TiDB v4.0.6"
+func ParseVersion(version string) (semver.Version, error) {
+	v, err := semver.Parse(version)
+	if err == nil {
+		return v, nil
+	}
+	if match := versionPattern.FindString(version); match != "" {
+		if v, err := semver.Parse(match); err == nil {
+			return v, nil
+		}
+	}
+	return v, errors.Errorf("no version in %s", version)
+}
+
+// Resolver maps versions to release branches of repos, and makes sure the branches exist
+type Resolver struct {
+	Forges *forge.Registry
+	// Templates are branch templates of repos tried in order, repos not in it use Default
+	Templates map[types.Repo][]string
+	Default   []string
+
+	mu sync.Mutex
+	// branches caches whether looked up branches of repos exist during a run
+	branches map[types.Repo]map[string]bool
+}
+
+// Resolve gets the first existing branch expanded from templates of repo by version
+func (r *Resolver) Resolve(ctx context.Context, repo types.Repo, version string) (string, error) {
+	v, err := ParseVersion(version)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	templates, ok := r.Templates[repo]
+	if !ok {
+		templates = r.Default
+	}
+	if len(templates) == 0 {
+		templates = DefaultTemplates
+	}
+	var candidates []string
+	for _, template := range templates {
+		name := Expand(template, v)
+		exists, err := r.branchExists(ctx, repo, name)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		if exists {
+			return name, nil
+		}
+		candidates = append(candidates, name)
+	}
+	return "", errors.Errorf("no branch of %s in %s, tried %s", version, repo, strings.Join(candidates, ", "))
+}
+
+// branchExists looks up branch of repo directly instead of listing all refs
+func (r *Resolver) branchExists(ctx context.Context, repo types.Repo, name string) (bool, error) {
+	r.mu.Lock()
+	exists, ok := r.branches[repo][name]
+	r.mu.Unlock()
+	if ok {
+		return exists, nil
+	}
+
+	// lookups run in parallel, a branch may be looked up twice
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
+	_, err := r.Forges.For(repo).GetRef(ctx, repo, "heads/"+name)
+	if err != nil && errors.Cause(err) != forge.ErrRefNotFound {
+		return false, errors.Trace(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.branches == nil {
+		r.branches = make(map[types.Repo]map[string]bool)
+	}
+	if r.branches[repo] == nil {
+		r.branches[repo] = make(map[string]bool)
+	}
+	r.branches[repo][name] = err == nil
+	return err == nil, nil
+}
@@ -0,0 +1,105 @@
+package branch
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you06/releaser/pkg/forge"
+	"github.com/you06/releaser/pkg/githubtest"
+	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
+)
+
+func TestExpand(t *testing.T) {
+	for _, c := range []struct {
+		template, version, branch string
+	}{
+		{"release-{major}.{minor}", "v4.0.6", "release-4.0"},
+		{"release-{major}.{minor}", "v5.1.2", "release-5.1"},
+		{"release-{major}.{minor}", "v5.0.0-rc.1+build.3", "release-5.0"},
+		{"release-{version}", "v5.0.0-rc.1+build.3", "release-5.0.0-rc.1"},
+		{"{major}.{minor}.{patch}-{pre-release}", "v5.0.0-rc", "5.0.0-rc"},
+		{"release-{major}.{minor}", "TiDB v5.1", "release-5.1"},
+	} {
+		v, err := ParseVersion(c.version)
+		require.Nil(t, err, c.version)
+		assert.Equal(t, Expand(c.template, v), c.branch, c.version)
+	}
+
+	_, err := ParseVersion("next")
+	assert.NotNil(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	server := githubtest.NewServer()
+	defer server.Close()
+	tidb := types.Repo{Owner: "pingcap", Repo: "tidb"}
+	pd := types.Repo{Owner: "pingcap", Repo: "pd"}
+	for _, repo := range []types.Repo{tidb, pd} {
+		server.AddRepo(repo)
+		server.AddRef(repo, "refs/tags/v5.1.0", "sha")
+	}
+	server.AddRef(tidb, "refs/heads/release-5.1", "sha")
+	server.AddRef(tidb, "refs/heads/release-5.3-hotfix", "sha")
+	server.AddRef(pd, "refs/heads/release-5.0", "sha")
+
+	g, err := forge.NewGitHub(server.APIURL(), "", "", "")
+	require.Nil(t, err)
+	r := Resolver{
+		Forges: forge.NewSingle(g),
+		Templates: map[types.Repo][]string{
+			pd: {"release-{major}.{minor}", "release-{major}.0"},
+		},
+	}
+
+	ctx := context.Background()
+	branch, err := r.Resolve(ctx, tidb, "v5.1.2")
+	require.Nil(t, err)
+	assert.Equal(t, branch, "release-5.1")
+	branch, err = r.Resolve(ctx, pd, "v5.1.2")
+	require.Nil(t, err)
+	assert.Equal(t, branch, "release-5.0")
+
+	_, err = r.Resolve(ctx, tidb, "v5.2.0")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "release-5.2")
+	// refs start with the branch don't match
+	_, err = r.Resolve(ctx, tidb, "v5.3.0")
+	assert.NotNil(t, err)
+	_, err = r.Resolve(ctx, tidb, "next")
+	assert.NotNil(t, err)
+
+	// branches are looked up directly once, refs are never listed
+	_, err = r.Resolve(ctx, tidb, "v5.1.3")
+	require.Nil(t, err)
+	assert.Equal(t, server.CountRequests("GET /repos/pingcap/tidb/git/refs/heads/release-5.1"), 1)
+	assert.Equal(t, server.CountRequests("GET /repos/pingcap/tidb/git/refs"), 3)
+	assert.Equal(t, server.CountRequests("GET /repos/pingcap/pd/git/refs"), 2)
+}
+
+func TestResolveParallel(t *testing.T) {
+	server := githubtest.NewServer()
+	defer server.Close()
+	var repos []types.Repo
+	for i := 0; i < 8; i++ {
+		repo := types.Repo{Owner: "pingcap", Repo: fmt.Sprintf("repo%d", i)}
+		server.AddRepo(repo)
+		server.AddRef(repo, "refs/heads/release-5.1", "sha")
+		repos = append(repos, repo)
+	}
+	g, err := forge.NewGitHub(server.APIURL(), "", "", "")
+	require.Nil(t, err)
+	r := Resolver{Forges: forge.NewSingle(g)}
+
+	err = utils.Parallel(context.Background(), len(repos), len(repos)*2, func(ctx context.Context, i int) error {
+		branch, err := r.Resolve(ctx, repos[i%len(repos)], "v5.1.2")
+		if err == nil && branch != "release-5.1" {
+			err = fmt.Errorf("got branch %s", branch)
+		}
+		return err
+	})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,156 @@
+package classify
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/you06/releaser/config"
+)
+
+var (
+	// DefaultTitleTypes maps conventional commit types in pull titles to release note types if they are not configured
+	DefaultTitleTypes = map[string]string{
+		"feat": "New Features",
+		"fix":  "Bug Fixes",
+		"perf": "Improvements",
+	}
+
+	// titlePattern matches conventional commit titles like "fix(executor)!: message"
+	titlePattern = regexp.MustCompile(`^(\w+)(?:\([^)]*\))?!?:\s`)
+)
+
+// Rule maps labels which match a glob or a regular expression to a type
+type Rule struct {
+	// Label is a glob like "type/*", or a regular expression in slashes like "/^type\/(bug|fix)$/"
+	Label string
+	Type  string
+	match func(label string) bool
+}
+
+// NewRule compiles the label pattern of rule
+func NewRule(label, tp string) (Rule, error) {
+	r := Rule{Label: label, Type: tp}
+	if len(label) > 1 && strings.HasPrefix(label, "/") && strings.HasSuffix(label, "/") {
+		re, err := regexp.Compile(label[1 : len(label)-1])
+		if err != nil {
+			return r, errors.Trace(err)
+		}
+		r.match = re.MatchString
+		return r, nil
+	}
+	if _, err := path.Match(label, ""); err != nil {
+		return r, errors.Errorf("invalid label glob %s", label)
+	}
+	r.match = func(l string) bool {
+		matched, _ := path.Match(label, l)
+		return matched
+	}
+	return r, nil
+}
+
+// Match checks if label matches the rule
+func (r Rule) Match(label string) bool {
+	return r.match(label)
+}
+
+// Classifier decides types of pulls by labels, then by titles
+type Classifier struct {
+	// Rules are in precedence order
+	Rules []Rule
+	// TitleTypes maps conventional commit types to types, used if no label matches
+	TitleTypes map[string]string
+	// Multi puts a pull in the type of every matched rule, instead of the first one
+	Multi bool
+}
+
+// New creates Classifier of product. Rules of label-rule come first in their order,
+// then label2type ordered by the position of their types in typeOrder, the rest types follow by label.
+func New(product config.Product, typeOrder []string) (*Classifier, error) {
+	c := Classifier{
+		TitleTypes: product.TitleTypes,
+		Multi:      product.MultiType,
+	}
+	if len(c.TitleTypes) == 0 {
+		c.TitleTypes = DefaultTitleTypes
+	}
+	for _, rule := range product.LabelRules {
+		r, err := NewRule(rule.Label, rule.Type)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		c.Rules = append(c.Rules, r)
+	}
+
+	rank := func(label string) int {
+		for i, tp := range typeOrder {
+			if tp == product.Label2Type[label] {
+				return i
+			}
+		}
+		return len(typeOrder)
+	}
+	var labels []string
+	for label := range product.Label2Type {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if ri, rj := rank(labels[i]), rank(labels[j]); ri != rj {
+			return ri < rj
+		}
+		return labels[i] < labels[j]
+	})
+	for _, label := range labels {
+		r, err := NewRule(label, product.Label2Type[label])
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		c.Rules = append(c.Rules, r)
+	}
+	return &c, nil
+}
+
+// Classify gets types of a pull, it's empty if nothing matches.
+// The type of the first rule matching any label is used, or types of all matched rules if Multi is set.
+// If no label matches, the conventional commit type of title is used.
+func (c *Classifier) Classify(title string, labels []string) []string {
+	var res []string
+	for _, rule := range c.Rules {
+		if !matchAny(rule, labels) || contains(res, rule.Type) {
+			continue
+		}
+		res = append(res, rule.Type)
+		if !c.Multi {
+			break
+		}
+	}
+	if len(res) > 0 {
+		return res
+	}
+	if match := titlePattern.FindStringSubmatch(title); len(match) == 2 {
+		if tp, ok := c.TitleTypes[strings.ToLower(match[1])]; ok {
+			return []string{tp}
+		}
+	}
+	return nil
+}
+
+func matchAny(rule Rule, labels []string) bool {
+	for _, label := range labels {
+		if rule.Match(label) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
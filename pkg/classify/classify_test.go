@@ -0,0 +1,66 @@
+package classify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you06/releaser/config"
+)
+
+var testTypeOrder = []string{"Compatibility Changes", "New Features", "Improvements", "Bug Fixes"}
+
+func TestClassifyPrecedence(t *testing.T) {
+	c, err := New(config.Product{
+		Label2Type: map[string]string{
+			"type/bug-fix":          "Bug Fixes",
+			"compatibility-breaker": "Compatibility Changes",
+			"type/new-feature":      "New Features",
+		},
+	}, testTypeOrder)
+	require.Nil(t, err)
+	assert.Equal(t, c.Classify("title", []string{"type/bug-fix", "compatibility-breaker"}), []string{"Compatibility Changes"})
+	assert.Equal(t, c.Classify("title", []string{"type/bug-fix", "type/new-feature"}), []string{"New Features"})
+	assert.Equal(t, c.Classify("title", []string{"status/LGT2"}), []string(nil))
+
+	c.Multi = true
+	assert.Equal(t, c.Classify("title", []string{"type/bug-fix", "compatibility-breaker", "type/new-feature"}),
+		[]string{"Compatibility Changes", "New Features", "Bug Fixes"})
+}
+
+func TestClassifyPattern(t *testing.T) {
+	c, err := New(config.Product{
+		LabelRules: []config.LabelRule{
+			{Label: "type/bug-fix", Type: "Bug Fixes"},
+			{Label: "/^(breaking|compatibility)-/", Type: "Compatibility Changes"},
+			{Label: "type/*", Type: "Improvements"},
+		},
+		Label2Type: map[string]string{"type/new-feature": "New Features"},
+	}, testTypeOrder)
+	require.Nil(t, err)
+	assert.Equal(t, c.Classify("title", []string{"type/enhancement"}), []string{"Improvements"})
+	assert.Equal(t, c.Classify("title", []string{"type/bug-fix", "breaking-change"}), []string{"Bug Fixes"})
+	assert.Equal(t, c.Classify("title", []string{"compatibility-breaker"}), []string{"Compatibility Changes"})
+	// label-rule comes before label2type
+	assert.Equal(t, c.Classify("title", []string{"type/new-feature"}), []string{"Improvements"})
+
+	_, err = New(config.Product{LabelRules: []config.LabelRule{{Label: "/(/", Type: "Bug Fixes"}}}, testTypeOrder)
+	assert.NotNil(t, err)
+	_, err = New(config.Product{LabelRules: []config.LabelRule{{Label: "type/[", Type: "Bug Fixes"}}}, testTypeOrder)
+	assert.NotNil(t, err)
+}
+
+func TestClassifyTitle(t *testing.T) {
+	c, err := New(config.Product{Label2Type: map[string]string{"type/bug-fix": "Bug Fixes"}}, testTypeOrder)
+	require.Nil(t, err)
+	assert.Equal(t, c.Classify("feat: support new syntax", nil), []string{"New Features"})
+	assert.Equal(t, c.Classify("Fix(executor)!: panic on kill", nil), []string{"Bug Fixes"})
+	assert.Equal(t, c.Classify("feat: support new syntax", []string{"type/bug-fix"}), []string{"Bug Fixes"})
+	assert.Equal(t, c.Classify("executor: fix panic", nil), []string(nil))
+	assert.Equal(t, c.Classify("fix panic", nil), []string(nil))
+
+	c, err = New(config.Product{TitleTypes: map[string]string{"docs": "Documents"}}, testTypeOrder)
+	require.Nil(t, err)
+	assert.Equal(t, c.Classify("docs: add readme", nil), []string{"Documents"})
+	assert.Equal(t, c.Classify("fix: panic", nil), []string(nil))
+}
@@ -1,6 +1,7 @@
 package dependency
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/you06/releaser/config"
+	"github.com/you06/releaser/pkg/forge"
 	"github.com/you06/releaser/pkg/git"
 	"github.com/you06/releaser/pkg/types"
 	"github.com/you06/releaser/pkg/utils"
@@ -32,14 +34,14 @@ var (
 // Dependency struct
 type Dependency struct {
 	Config *config.Config
-	Github *github.Client
+	Forges *forge.Registry
 	User   *github.User
 }
 
 // Config struct
 type Config struct {
 	Config *config.Config
-	Github *github.Client
+	Forges *forge.Registry
 	User   *github.User
 }
 
@@ -47,24 +49,24 @@ type Config struct {
 func New(cfg *Config) *Dependency {
 	return &Dependency{
 		Config: cfg.Config,
-		Github: cfg.Github,
+		Forges: cfg.Forges,
 		User:   cfg.User,
 	}
 }
 
 // GetDependencies get all dependencies in a version
-func (d *Dependency) GetDependencies(repo types.Repo, version string) ([]*types.Package, error) {
+func (d *Dependency) GetDependencies(ctx context.Context, repo types.Repo, version string) ([]*types.Package, error) {
 	var packages []*types.Package
 
 	ref := version
-	contents, err := d.ListContents(repo, version)
+	contents, err := d.ListContents(ctx, repo, version)
 	if err != nil {
 		if strings.Contains(err.Error(), "No commit found") {
-			ref, err = d.GetVersionRef(repo, version)
+			ref, err = d.GetVersionRef(ctx, repo, version)
 			if err != nil {
 				return packages, errors.Trace(err)
 			}
-			contents, err = d.ListContents(repo, ref)
+			contents, err = d.ListContents(ctx, repo, ref)
 			if err != nil {
 				return packages, errors.Trace(err)
 			}
@@ -87,7 +89,7 @@ func (d *Dependency) GetDependencies(repo types.Repo, version string) ([]*types.
 			}
 		}
 		if match {
-			content, err := d.GetContent(repo, ref, filename)
+			content, err := d.GetContent(ctx, repo, ref, filename)
 			if err != nil {
 				return packages, errors.Trace(err)
 			}
@@ -111,9 +113,10 @@ func (d *Dependency) GetDependencies(repo types.Repo, version string) ([]*types.
 // TODO: it's too slow, find a better way to get sha
 func (d *Dependency) GetVersionSHA(repo types.Repo, version string) (string, error) {
 	gitClient := git.New(d.Config, &git.Config{
-		User: d.User,
-		Base: repo,
-		Dir:  fmt.Sprintf("%s-%s", repo.Repo, version),
+		Forge: d.Forges.For(repo),
+		User:  d.User,
+		Base:  repo,
+		Dir:   fmt.Sprintf("%s-%s", repo.Repo, version),
 	})
 	if err := gitClient.Clone(); err != nil {
 		return "", errors.Trace(err)
@@ -128,29 +131,11 @@ func (d *Dependency) GetVersionSHA(repo types.Repo, version string) (string, err
 }
 
 // GetVersionRef gets ref by a version
-func (d *Dependency) GetVersionRef(repo types.Repo, version string) (string, error) {
+func (d *Dependency) GetVersionRef(ctx context.Context, repo types.Repo, version string) (string, error) {
 	version = strings.TrimLeft(version, "v")
-	ctx, _ := utils.NewTimeoutContext()
-
-	var (
-		refs    []*github.Reference
-		batch   []*github.Reference
-		page    = 0
-		perpage = 100
-	)
-
-	for page == 0 || len(batch) == perpage {
-		page++
-		batch, _, err := d.Github.Git.ListRefs(ctx, repo.Owner, repo.Repo, &github.ReferenceListOptions{
-			ListOptions: github.ListOptions{
-				Page:    page,
-				PerPage: perpage,
-			},
-		})
-		if err != nil {
-			return "", errors.Trace(err)
-		}
-		refs = append(refs, batch...)
+	refs, err := d.Forges.For(repo).ListRefs(ctx, repo, "")
+	if err != nil {
+		return "", errors.Trace(err)
 	}
 
 	if sha, match := matchRef(refs, version); match {
@@ -174,13 +159,11 @@ func matchRef(refs []*github.Reference, version string) (string, bool) {
 }
 
 // ListContents list contents in a ref
-func (d *Dependency) ListContents(repo types.Repo, sha string) ([]*github.RepositoryContent, error) {
-	ctx, _ := utils.NewTimeoutContext()
+func (d *Dependency) ListContents(ctx context.Context, repo types.Repo, sha string) ([]*github.RepositoryContent, error) {
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
 	// TODO: what will happen if there are more than 100 files?
-	_, contents, _, err := d.Github.Repositories.GetContents(ctx,
-		repo.Owner, repo.Repo, "", &github.RepositoryContentGetOptions{
-			Ref: sha,
-		})
+	_, contents, err := d.Forges.For(repo).GetContents(ctx, repo, "", sha)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -188,12 +171,10 @@ func (d *Dependency) ListContents(repo types.Repo, sha string) ([]*github.Reposi
 }
 
 // GetContent get specific content in a ref
-func (d *Dependency) GetContent(repo types.Repo, sha, filename string) (*github.RepositoryContent, error) {
-	ctx, _ := utils.NewTimeoutContext()
-	content, _, _, err := d.Github.Repositories.GetContents(ctx,
-		repo.Owner, repo.Repo, filename, &github.RepositoryContentGetOptions{
-			Ref: sha,
-		})
+func (d *Dependency) GetContent(ctx context.Context, repo types.Repo, sha, filename string) (*github.RepositoryContent, error) {
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
+	content, _, err := d.Forges.For(repo).GetContents(ctx, repo, filename, sha)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -0,0 +1,171 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	opEqual = iota
+	opDelete
+	opInsert
+)
+
+type edit struct {
+	op   int
+	line string
+}
+
+// Unified returns unified diff between lines of two texts,
+// empty string is returned if there is no hunk, line endings and trailing newline are ignored
+func Unified(a, b, fromName, toName string, context int) string {
+	h := hunks(lineEdits(splitLines(a), splitLines(b)), context)
+	if len(h) == 0 {
+		return ""
+	}
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range h {
+		out.WriteString(hunk)
+	}
+	return out.String()
+}
+
+// splitLines splits text into lines without CR
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// lineEdits computes edits by longest common subsequence,
+// release notes are small enough for the quadratic algorithm
+func lineEdits(a, b []string) []edit {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var (
+		edits []edit
+		i, j  int
+	)
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{opEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{opDelete, a[i]})
+			i++
+		default:
+			edits = append(edits, edit{opInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, edit{opDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, edit{opInsert, b[j]})
+	}
+	return edits
+}
+
+// hunks groups edits into hunks with context lines
+func hunks(edits []edit, context int) []string {
+	var (
+		res   []string
+		start = -1
+		end   = -1
+	)
+	flush := func() {
+		if start < 0 {
+			return
+		}
+		res = append(res, formatHunk(edits, start, end))
+		start, end = -1, -1
+	}
+	for i, e := range edits {
+		if e.op == opEqual {
+			continue
+		}
+		from := max(i-context, 0)
+		if start >= 0 && from > end {
+			flush()
+		}
+		if start < 0 {
+			start = from
+		}
+		end = min(i+context+1, len(edits))
+	}
+	flush()
+	return res
+}
+
+func formatHunk(edits []edit, start, end int) string {
+	var (
+		b              strings.Builder
+		body           strings.Builder
+		aStart, bStart = 1, 1
+		aLines, bLines int
+		prefix         = map[int]string{opEqual: " ", opDelete: "-", opInsert: "+"}
+	)
+	for _, e := range edits[:start] {
+		if e.op != opInsert {
+			aStart++
+		}
+		if e.op != opDelete {
+			bStart++
+		}
+	}
+	for _, e := range edits[start:end] {
+		if e.op != opInsert {
+			aLines++
+		}
+		if e.op != opDelete {
+			bLines++
+		}
+		body.WriteString(prefix[e.op])
+		body.WriteString(e.line)
+		body.WriteString("\n")
+	}
+	// an empty range starts at the line before it
+	if aLines == 0 {
+		aStart--
+	}
+	if bLines == 0 {
+		bStart--
+	}
+	fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", aStart, aLines, bStart, bLines)
+	b.WriteString(body.String())
+	return b.String()
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,19 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnified(t *testing.T) {
+	assert.Equal(t, Unified("a\nb\n", "a\nb\n", "old", "new", 3), "")
+	// line endings make no hunk
+	assert.Equal(t, Unified("a\nb", "a\nb\n", "old", "new", 3), "")
+	assert.Equal(t, Unified("a\r\nb\r\n", "a\nb\n", "old", "new", 3), "")
+	assert.Equal(t, Unified("", "a\nb\n", "old", "new", 3), "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n")
+	assert.Equal(t, Unified("1\n2\n3\n4\n5\n6\n7\n8\n", "1\n2\n3\nx\n5\n6\n7\n8\n", "old", "new", 1),
+		"--- old\n+++ new\n@@ -3,3 +3,3 @@\n 3\n-4\n+x\n 5\n")
+	assert.Equal(t, Unified("1\n2\n3\n4\n5\n6\n7\n8\n", "0\n1\n2\n3\n4\n5\n6\n7\n", "old", "new", 1),
+		"--- old\n+++ new\n@@ -1,1 +1,2 @@\n+0\n 1\n@@ -7,2 +8,1 @@\n 7\n-8\n")
+}
@@ -0,0 +1,284 @@
+package extract
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/you06/releaser/config"
+)
+
+// CODE_BLOCK is the info string of fenced code blocks which contain release notes,
+// like "```release-note", "```release-note-cn" is the note in cn
+const CODE_BLOCK = "release-note"
+
+var (
+	// DefaultHeading is the heading of release note in pull-language if it's not configured
+	DefaultHeading = `^.*release ?note.*$`
+	// DefaultEndHeading is the heading which ends release notes if it's not configured
+	DefaultEndHeading = `^\#{1,3}\ .*$`
+	// DefaultNAPhrases mean there is no release note if they are not configured
+	DefaultNAPhrases = []string{"no release note", "na", "no need", "none", "no", "no. it's trivial", "n/a"}
+
+	commentPattern = regexp.MustCompile(`<!--[^>]*-->`)
+	bulletPattern  = regexp.MustCompile(`^(?:[-+]|\*\s)\s*(.*)$`)
+	fencePattern   = regexp.MustCompile("^(```|~~~)\\s*(\\S*)")
+)
+
+// Profile is the rule to extract release notes from pull requests
+type Profile struct {
+	// Lang is the language of notes in code block without language suffix
+	Lang string
+	// Headings maps languages to headings of release notes
+	Headings map[string]*regexp.Regexp
+	// EndHeading matches headings which end release notes
+	EndHeading *regexp.Regexp
+	// NA matches notes which mean there is no release note
+	NA *regexp.Regexp
+	// NoNoteLabels mean the pull request has no release note
+	NoNoteLabels []string
+	// CodeBlock reads release notes from CODE_BLOCK fenced code blocks before headings
+	CodeBlock bool
+}
+
+// NewProfile creates profile from config, empty fields of config use the default rules in lang
+func NewProfile(cfg config.Extraction, lang string) (*Profile, error) {
+	var (
+		p = Profile{
+			Lang:         lang,
+			Headings:     make(map[string]*regexp.Regexp),
+			NoNoteLabels: cfg.NoNoteLabels,
+			CodeBlock:    cfg.CodeBlock,
+		}
+		headings   = cfg.Headings
+		endHeading = cfg.EndHeading
+		naPhrases  = cfg.NAPhrases
+		err        error
+	)
+	if len(headings) == 0 {
+		headings = map[string]string{lang: DefaultHeading}
+	}
+	if endHeading == "" {
+		endHeading = DefaultEndHeading
+	}
+	if len(naPhrases) == 0 {
+		naPhrases = DefaultNAPhrases
+	}
+	for l, heading := range headings {
+		if p.Headings[l], err = regexp.Compile("(?i)" + heading); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if p.EndHeading, err = regexp.Compile(endHeading); err != nil {
+		return nil, errors.Trace(err)
+	}
+	var phrases []string
+	for _, phrase := range naPhrases {
+		phrases = append(phrases, regexp.QuoteMeta(strings.TrimRight(strings.ToLower(phrase), ".")))
+	}
+	p.NA = regexp.MustCompile(`^\s*` + "`?" + `\(?(` + strings.Join(phrases, "|") + `)\.?\)?` + "`?" + `\.?\s*$`)
+	return &p, nil
+}
+
+// DefaultProfile creates profile of default rules in lang
+func DefaultProfile(lang string) *Profile {
+	p, _ := NewProfile(config.Extraction{}, lang)
+	return p
+}
+
+// Extract gets bullets of release notes in languages from pull request body.
+// Notes in CODE_BLOCK fenced code blocks are used if CodeBlock is set and there are such blocks,
+// otherwise notes of a language are lines after the line matches its heading and before the heading of another language.
+// Languages without notes or with N/A notes are not in the result, and there is no note if any label is in NoNoteLabels.
+func (p *Profile) Extract(body string, labels []string) map[string][]string {
+	notes := make(map[string][]string)
+	for _, label := range labels {
+		for _, noNote := range p.NoNoteLabels {
+			if strings.EqualFold(label, noNote) {
+				return notes
+			}
+		}
+	}
+
+	body = commentPattern.ReplaceAllString(body, "")
+	body = strings.ReplaceAll(body, "\r", "")
+	lines := strings.Split(body, "\n")
+
+	langLines := p.codeBlocks(lines)
+	if len(langLines) == 0 {
+		langLines = p.sections(lines)
+	}
+	for l, lines := range langLines {
+		if bullets := p.bullets(lines); len(bullets) > 0 {
+			notes[l] = bullets
+		}
+	}
+	return notes
+}
+
+// codeBlocks gets lines in CODE_BLOCK fenced code blocks by language
+func (p *Profile) codeBlocks(lines []string) map[string][]string {
+	res := make(map[string][]string)
+	if !p.CodeBlock {
+		return res
+	}
+	var (
+		fence string
+		lang  string
+		found bool
+	)
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence, found = "", false
+			} else if found {
+				res[lang] = append(res[lang], line)
+			}
+			continue
+		}
+		if match := fencePattern.FindStringSubmatch(trimmed); len(match) == 3 {
+			fence = match[1]
+			info := strings.ToLower(match[2])
+			if info == CODE_BLOCK {
+				lang, found = p.Lang, true
+			} else if strings.HasPrefix(info, CODE_BLOCK+"-") {
+				lang, found = strings.TrimPrefix(info, CODE_BLOCK+"-"), true
+			}
+			if found {
+				// headings are not used once there is a block, even if it's empty
+				res[lang] = append(res[lang], "")
+			}
+		}
+	}
+	return res
+}
+
+// sections gets lines of release note sections by language
+func (p *Profile) sections(lines []string) map[string][]string {
+	var (
+		langs = make([]string, 0, len(p.Headings))
+		res   = make(map[string][]string)
+		lang  string
+		found bool
+	)
+	for l := range p.Headings {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		heading := false
+		for _, l := range langs {
+			// notes may mention their own heading, like "fix release note generation"
+			if (!found || l != lang) && p.Headings[l].MatchString(strings.ToLower(trimmed)) {
+				lang, found, heading = l, true, true
+				break
+			}
+		}
+		if found && !heading {
+			res[lang] = append(res[lang], line)
+		}
+	}
+	return res
+}
+
+// bullets gets every bullet of release note before the next heading,
+// text without bullet is a note too. Lines indented under a bullet and fenced code are kept in the bullet.
+// There is no note if the first bullet is N/A.
+func (p *Profile) bullets(lines []string) []string {
+	var (
+		bullets []string
+		current []string
+		fence   string
+		blank   bool
+	)
+	flush := func() {
+		if len(current) > 0 {
+			bullets = append(bullets, joinBullet(current))
+			current = nil
+		}
+	}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			current = append(current, line)
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		case trimmed == "":
+			blank = true
+			if len(current) > 0 {
+				current = append(current, "")
+			}
+			continue
+		case p.EndHeading.MatchString(trimmed):
+			flush()
+			return p.checkNA(bullets)
+		}
+
+		if match := fencePattern.FindStringSubmatch(trimmed); len(match) == 3 {
+			fence = match[1]
+			if len(current) == 0 || (blank && !indented(line)) {
+				flush()
+			}
+			current = append(current, line)
+		} else if match := bulletPattern.FindStringSubmatch(trimmed); len(match) == 2 && (len(current) == 0 || !indented(line)) {
+			flush()
+			current = []string{match[1]}
+		} else if len(current) > 0 && (!blank || indented(line)) {
+			current = append(current, line)
+		} else {
+			flush()
+			current = []string{trimmed}
+		}
+		blank = false
+	}
+	flush()
+	return p.checkNA(bullets)
+}
+
+func (p *Profile) checkNA(bullets []string) []string {
+	if len(bullets) == 0 || p.NA.MatchString(strings.ToLower(bullets[0])) {
+		return nil
+	}
+	return bullets
+}
+
+func indented(line string) bool {
+	return strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t")
+}
+
+// joinBullet joins the first line of bullet with the rest lines dedented
+func joinBullet(lines []string) string {
+	var (
+		rest   = lines[1:]
+		dedent = -1
+	)
+	for len(rest) > 0 && strings.TrimSpace(rest[len(rest)-1]) == "" {
+		rest = rest[:len(rest)-1]
+	}
+	for i, line := range rest {
+		rest[i] = strings.ReplaceAll(line, "\t", "    ")
+		if strings.TrimSpace(rest[i]) == "" {
+			continue
+		}
+		if n := len(rest[i]) - len(strings.TrimLeft(rest[i], " ")); dedent < 0 || n < dedent {
+			dedent = n
+		}
+	}
+	res := []string{strings.TrimSpace(lines[0])}
+	for _, line := range rest {
+		if strings.TrimSpace(line) == "" {
+			line = ""
+		} else {
+			line = strings.TrimRight(line[dedent:], " ")
+		}
+		res = append(res, line)
+	}
+	return strings.Join(res, "\n")
+}
@@ -0,0 +1,94 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you06/releaser/config"
+)
+
+type hasReleaseNoteRes struct {
+	note string
+	has  bool
+}
+
+func testHasReleaseNote(raw string) hasReleaseNoteRes {
+	notes := DefaultProfile("en").Extract(raw, nil)
+	if len(notes["en"]) == 0 {
+		return hasReleaseNoteRes{"", false}
+	}
+	return hasReleaseNoteRes{notes["en"][0], true}
+}
+
+func TestHasReleaseNote(t *testing.T) {
+	assert.Equal(t, testHasReleaseNote("Bug Fix \nRelease note\n- NA"), hasReleaseNoteRes{"", false}, "case 1")
+	assert.Equal(t, testHasReleaseNote("Bug Fix \nRelease note\n- N/A"), hasReleaseNoteRes{"", false}, "case 2")
+	assert.Equal(t, testHasReleaseNote("Bug Fix \nRelease note\n- (N/A)"), hasReleaseNoteRes{"", false}, "case 3")
+	assert.Equal(t, testHasReleaseNote("### Release note <!-- bugfixes or new feature need a release note -->\n\n* No release note"), hasReleaseNoteRes{"", false}, "case 4")
+	assert.Equal(t, testHasReleaseNote("### Release note <!-- bugfixes or new feature need a release note -->\n- No release note."), hasReleaseNoteRes{"", false}, "case 5")
+	assert.Equal(t, testHasReleaseNote("### Release note <!-- bugfixes or new feature need a release note -->\n- `No release note`"), hasReleaseNoteRes{"", false}, "case 6")
+	assert.Equal(t, testHasReleaseNote("### Release note <!-- bugfixes or new feature need a release note -->\n- `No release note`."), hasReleaseNoteRes{"", false}, "case 6")
+	assert.Equal(t, testHasReleaseNote("### Release note\n- fix a panic"), hasReleaseNoteRes{"fix a panic", true}, "case 7")
+}
+
+func TestExtractLanguages(t *testing.T) {
+	p, err := NewProfile(config.Extraction{Headings: map[string]string{"en": "release note", "cn": "发布说明"}}, "en")
+	require.Nil(t, err)
+	body := "### What is changed\n\nfix bug\n\n### Release note\n\n- fix the release note of planner\n\n### 发布说明\n\n- 修复优化器的发布说明"
+	assert.Equal(t, p.Extract(body, nil), map[string][]string{
+		"en": {"fix the release note of planner"},
+		"cn": {"修复优化器的发布说明"},
+	})
+	assert.Equal(t, p.Extract("### 发布说明\n- 修复\n### Release note\n- No release note", nil),
+		map[string][]string{"cn": {"修复"}})
+}
+
+func TestExtractBullets(t *testing.T) {
+	body := `### Release note
+
+- Support ` + "`SHOW CONFIG`" + ` for **all** components
+  in the cluster
+* Fix a panic:
+
+  ` + "```sql" + `
+  - not a bullet
+  ` + "```" + `
++ Improve performance
+
+plain text note
+  continued
+
+### Others
+
+- not a note`
+	assert.Equal(t, DefaultProfile("en").Extract(body, nil)["en"], []string{
+		"Support `SHOW CONFIG` for **all** components\nin the cluster",
+		"Fix a panic:\n\n```sql\n- not a bullet\n```",
+		"Improve performance",
+		"plain text note\ncontinued",
+	})
+}
+
+func TestExtractProfile(t *testing.T) {
+	p, err := NewProfile(config.Extraction{
+		Headings:     map[string]string{"en": `^#+ release notes?$`},
+		EndHeading:   `^-{3,}$`,
+		NAPhrases:    []string{"NONE", "nothing."},
+		NoNoteLabels: []string{"release-note-none"},
+		CodeBlock:    true,
+	}, "en")
+	require.Nil(t, err)
+
+	// Kubernetes style code blocks are read before headings
+	body := "#### Release note\n- from heading\n\n```release-note\nfix a panic\n```\n\n```release-note-cn\n修复崩溃\n```"
+	assert.Equal(t, p.Extract(body, nil), map[string][]string{"en": {"fix a panic"}, "cn": {"修复崩溃"}})
+	assert.Equal(t, p.Extract("```release-note\nNONE\n```\n#### Release note\n- from heading", nil), map[string][]string{})
+	assert.Equal(t, p.Extract("#### Release note\n- from heading\n---\n- after end", nil), map[string][]string{"en": {"from heading"}})
+	assert.Equal(t, p.Extract("#### Release notes\n- Nothing", nil), map[string][]string{})
+	assert.Equal(t, p.Extract("#### Release note\n- None of errors is ignored", nil), map[string][]string{"en": {"None of errors is ignored"}})
+	assert.Equal(t, p.Extract("#### Release note\n- fix", []string{"Release-Note-None"}), map[string][]string{})
+
+	_, err = NewProfile(config.Extraction{EndHeading: "("}, "en")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,147 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/you06/releaser/config"
+	"github.com/you06/releaser/pkg/httpcache"
+	"github.com/you06/releaser/pkg/ratelimit"
+	"github.com/you06/releaser/pkg/types"
+)
+
+const (
+	// TypeGitHub is github.com or GitHub Enterprise
+	TypeGitHub = "github"
+	// TypeGitea is a Gitea instance
+	TypeGitea = "gitea"
+)
+
+var (
+	// ErrPullExists is returned by CreatePull when the same pull request is already opened
+	ErrPullExists = errors.New("pull request already exists")
+	// ErrRefNotFound is returned by GetRef when the ref doesn't exist
+	ErrRefNotFound = errors.New("ref not found")
+)
+
+// Forge is a code hosting service.
+// go-github types are used as the common data model,
+// so collectors can work with a single representation of milestones and pulls.
+type Forge interface {
+	// GetUser gets the authenticated user
+	GetUser(ctx context.Context) (*github.User, error)
+	// GetRepo gets repository info
+	GetRepo(ctx context.Context, repo types.Repo) (*github.Repository, error)
+	// CreateFork forks repo into the authenticated user's namespace
+	CreateFork(ctx context.Context, repo types.Repo) error
+	// ListMilestones lists all milestones in given state
+	ListMilestones(ctx context.Context, repo types.Repo, state string) ([]*github.Milestone, error)
+	// ListMilestoneIssues lists all issues and pulls in a milestone,
+	// pulls only carry the fields of issues, use GetPull for full info
+	ListMilestoneIssues(ctx context.Context, repo types.Repo, milestone *github.Milestone) ([]*github.Issue, error)
+	// GetPull gets a pull request by number
+	GetPull(ctx context.Context, repo types.Repo, number int) (*github.PullRequest, error)
+	// CreatePull opens a pull request, returns ErrPullExists if it's already opened
+	CreatePull(ctx context.Context, repo types.Repo, pull *github.NewPullRequest) (*github.PullRequest, error)
+	// GetContents gets a file or lists a directory at ref, empty ref means default branch
+	GetContents(ctx context.Context, repo types.Repo, path, ref string) (*github.RepositoryContent, []*github.RepositoryContent, error)
+	// ListRefs lists refs under prefix like "tags/", or all refs if prefix is empty
+	ListRefs(ctx context.Context, repo types.Repo, prefix string) ([]*github.Reference, error)
+	// GetRef gets a ref like "heads/master", returns ErrRefNotFound if it doesn't exist
+	GetRef(ctx context.Context, repo types.Repo, ref string) (*github.Reference, error)
+	// RemoteURL composes git remote address with credential of user
+	RemoteURL(repo types.Repo, user string) string
+}
+
+// Registry selects forge for repos
+type Registry struct {
+	Default Forge
+	forges  map[types.Repo]Forge
+}
+
+// New creates Registry from config, repos without a forge use GitHub by github-token,
+// responses are cached in cacheDir unless it's empty
+func New(cfg *config.Config, cacheDir string) (*Registry, error) {
+	def, err := NewGitHub("", "", cfg.GithubToken, cacheDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r := Registry{
+		Default: def,
+		forges:  make(map[types.Repo]Forge),
+	}
+	for _, forgeCfg := range cfg.Forges {
+		f, err := newForge(forgeCfg, cacheDir)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for _, repoStr := range forgeCfg.Repos {
+			repo, err := types.ParseRepo(repoStr)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			r.Set(repo, f)
+		}
+	}
+	return &r, nil
+}
+
+// NewSingle creates Registry which uses one forge for all repos
+func NewSingle(f Forge) *Registry {
+	return &Registry{
+		Default: f,
+		forges:  make(map[types.Repo]Forge),
+	}
+}
+
+// Set the forge of a repo
+func (r *Registry) Set(repo types.Repo, f Forge) {
+	r.forges[repo] = f
+}
+
+// For gets the forge of a repo
+func (r *Registry) For(repo types.Repo) Forge {
+	if f, ok := r.forges[repo]; ok {
+		return f
+	}
+	return r.Default
+}
+
+func newForge(cfg config.Forge, cacheDir string) (Forge, error) {
+	switch cfg.Type {
+	case "", TypeGitHub:
+		f, err := NewGitHub(cfg.APIURL, cfg.GitURL, cfg.Token, cacheDir)
+		return f, errors.Trace(err)
+	case TypeGitea:
+		f, err := NewGitea(cfg.APIURL, cfg.GitURL, cfg.Token, cacheDir)
+		return f, errors.Trace(err)
+	default:
+		return nil, errors.Errorf("forge %s has invalid type %s", cfg.Name, cfg.Type)
+	}
+}
+
+// newTransport creates the transport shared by forges, which retries on rate limits and transient errors,
+// and caches responses in cacheDir unless it's empty
+func newTransport(cacheDir string) http.RoundTripper {
+	var transport http.RoundTripper = ratelimit.NewTransport(nil)
+	if cacheDir != "" {
+		// revalidated requests also wait for rate limit
+		transport = httpcache.NewTransport(transport, cacheDir)
+	}
+	return transport
+}
+
+// remoteURL composes https git address with credential
+func remoteURL(gitURL *url.URL, repo types.Repo, user, token string) string {
+	u := *gitURL
+	if token != "" {
+		u.User = url.UserPassword(user, token)
+	}
+	u.Path = fmt.Sprintf("%s/%s/%s.git", strings.TrimRight(u.Path, "/"), repo.Owner, repo.Repo)
+	return u.String()
+}
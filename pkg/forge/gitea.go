@@ -0,0 +1,468 @@
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
+)
+
+// Gitea implements Forge by Gitea API v1
+type Gitea struct {
+	client  *http.Client
+	baseURL *url.URL
+	gitURL  *url.URL
+	token   string
+}
+
+type giteaUser struct {
+	Login string `json:"login"`
+	Email string `json:"email"`
+}
+
+type giteaLabel struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type giteaMilestone struct {
+	ID           int64      `json:"id"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	State        string     `json:"state"`
+	OpenIssues   int        `json:"open_issues"`
+	ClosedIssues int        `json:"closed_issues"`
+	Deadline     *time.Time `json:"due_on"`
+}
+
+type giteaPullMeta struct {
+	Merged bool `json:"merged"`
+}
+
+type giteaIssue struct {
+	ID          int64           `json:"id"`
+	Number      int             `json:"number"`
+	Title       string          `json:"title"`
+	Body        string          `json:"body"`
+	State       string          `json:"state"`
+	HTMLURL     string          `json:"html_url"`
+	User        giteaUser       `json:"user"`
+	Labels      []giteaLabel    `json:"labels"`
+	Milestone   *giteaMilestone `json:"milestone"`
+	PullRequest *giteaPullMeta  `json:"pull_request"`
+}
+
+type giteaBranch struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+type giteaPull struct {
+	ID             int64           `json:"id"`
+	Number         int             `json:"number"`
+	Title          string          `json:"title"`
+	Body           string          `json:"body"`
+	State          string          `json:"state"`
+	HTMLURL        string          `json:"html_url"`
+	User           giteaUser       `json:"user"`
+	Labels         []giteaLabel    `json:"labels"`
+	Milestone      *giteaMilestone `json:"milestone"`
+	Merged         bool            `json:"merged"`
+	MergedAt       *time.Time      `json:"merged_at"`
+	MergeCommitSHA string          `json:"merge_commit_sha"`
+	Base           giteaBranch     `json:"base"`
+	Head           giteaBranch     `json:"head"`
+}
+
+type giteaRepo struct {
+	ID       int64     `json:"id"`
+	Name     string    `json:"name"`
+	FullName string    `json:"full_name"`
+	Owner    giteaUser `json:"owner"`
+	HTMLURL  string    `json:"html_url"`
+	CloneURL string    `json:"clone_url"`
+	Fork     bool      `json:"fork"`
+}
+
+type giteaContent struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	SHA      string `json:"sha"`
+	Type     string `json:"type"`
+	Size     int    `json:"size"`
+	Encoding string `json:"encoding"`
+	Content  string `json:"content"`
+	HTMLURL  string `json:"html_url"`
+}
+
+type giteaRef struct {
+	Ref    string `json:"ref"`
+	Object struct {
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	} `json:"object"`
+}
+
+// NewGitea creates Gitea forge, apiURL is the root of instance like https://gitea.example.com,
+// gitURL defaults to apiURL, responses are cached in cacheDir unless it's empty
+func NewGitea(apiURL, gitURL, token, cacheDir string) (*Gitea, error) {
+	if apiURL == "" {
+		return nil, errors.New("gitea forge requires api-url")
+	}
+	baseURL, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	baseURL.Path = strings.TrimSuffix(strings.TrimRight(baseURL.Path, "/"), "/api/v1") + "/api/v1/"
+	if gitURL == "" {
+		gitURL = apiURL
+	}
+	u, err := url.Parse(gitURL)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &Gitea{
+		client:  &http.Client{Transport: newTransport(cacheDir)},
+		baseURL: baseURL,
+		gitURL:  u,
+		token:   token,
+	}, nil
+}
+
+// GetUser gets the authenticated user
+func (g *Gitea) GetUser(ctx context.Context) (*github.User, error) {
+	var user giteaUser
+	if err := g.do(ctx, http.MethodGet, "user", nil, &user); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return user.toGithub(), nil
+}
+
+// GetRepo gets repository info
+func (g *Gitea) GetRepo(ctx context.Context, repo types.Repo) (*github.Repository, error) {
+	var r giteaRepo
+	if err := g.do(ctx, http.MethodGet, repoPath(repo), nil, &r); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &github.Repository{
+		ID:       github.Int64(r.ID),
+		Name:     github.String(r.Name),
+		FullName: github.String(r.FullName),
+		Owner:    r.Owner.toGithub(),
+		HTMLURL:  github.String(r.HTMLURL),
+		CloneURL: github.String(r.CloneURL),
+		Fork:     github.Bool(r.Fork),
+	}, nil
+}
+
+// CreateFork forks repo into the authenticated user's namespace
+func (g *Gitea) CreateFork(ctx context.Context, repo types.Repo) error {
+	return errors.Trace(g.do(ctx, http.MethodPost, repoPath(repo)+"/forks", struct{}{}, nil))
+}
+
+// ListMilestones lists all milestones in given state
+func (g *Gitea) ListMilestones(ctx context.Context, repo types.Repo, state string) ([]*github.Milestone, error) {
+	var all []*github.Milestone
+	for page := 1; ; page++ {
+		var batch []giteaMilestone
+		p := fmt.Sprintf("%s/milestones?state=%s&page=%d&limit=%d", repoPath(repo), url.QueryEscape(state), page, perpage)
+		if err := g.doPage(ctx, p, &batch); err != nil {
+			return nil, errors.Trace(err)
+		}
+		for i := range batch {
+			all = append(all, batch[i].toGithub())
+		}
+		if len(batch) < perpage {
+			return all, nil
+		}
+	}
+}
+
+// ListMilestoneIssues lists all issues and pulls in a milestone
+func (g *Gitea) ListMilestoneIssues(ctx context.Context, repo types.Repo, milestone *github.Milestone) ([]*github.Issue, error) {
+	var all []*github.Issue
+	for page := 1; ; page++ {
+		var batch []giteaIssue
+		p := fmt.Sprintf("%s/issues?state=all&milestones=%d&page=%d&limit=%d",
+			repoPath(repo), milestone.GetID(), page, perpage)
+		if err := g.doPage(ctx, p, &batch); err != nil {
+			return all, errors.Trace(err)
+		}
+		for i := range batch {
+			all = append(all, batch[i].toGithub())
+		}
+		if len(batch) < perpage {
+			return all, nil
+		}
+	}
+}
+
+// GetPull gets a pull request by number
+func (g *Gitea) GetPull(ctx context.Context, repo types.Repo, number int) (*github.PullRequest, error) {
+	var pull giteaPull
+	if err := g.do(ctx, http.MethodGet, fmt.Sprintf("%s/pulls/%d", repoPath(repo), number), nil, &pull); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return pull.toGithub(), nil
+}
+
+// CreatePull opens a pull request
+func (g *Gitea) CreatePull(ctx context.Context, repo types.Repo, newPull *github.NewPullRequest) (*github.PullRequest, error) {
+	var (
+		pull giteaPull
+		body = map[string]string{
+			"title": newPull.GetTitle(),
+			"body":  newPull.GetBody(),
+			"head":  newPull.GetHead(),
+			"base":  newPull.GetBase(),
+		}
+	)
+	err := g.do(ctx, http.MethodPost, repoPath(repo)+"/pulls", body, &pull)
+	if err != nil && strings.Contains(err.Error(), "409 Conflict") {
+		return nil, ErrPullExists
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return pull.toGithub(), nil
+}
+
+// GetContents gets a file or lists a directory at ref
+func (g *Gitea) GetContents(ctx context.Context, repo types.Repo, path, ref string) (*github.RepositoryContent, []*github.RepositoryContent, error) {
+	p := fmt.Sprintf("%s/contents/%s", repoPath(repo), strings.TrimLeft(path, "/"))
+	if ref != "" {
+		p += "?ref=" + url.QueryEscape(ref)
+	}
+	var raw json.RawMessage
+	if err := g.do(ctx, http.MethodGet, p, nil, &raw); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	// a directory is listed as array while a file is an object
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		var contents []giteaContent
+		if err := json.Unmarshal(raw, &contents); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		var dir []*github.RepositoryContent
+		for i := range contents {
+			dir = append(dir, contents[i].toGithub())
+		}
+		return nil, dir, nil
+	}
+	var content giteaContent
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return content.toGithub(), nil, nil
+}
+
+// ListRefs lists refs under prefix, or all refs if prefix is empty
+func (g *Gitea) ListRefs(ctx context.Context, repo types.Repo, prefix string) ([]*github.Reference, error) {
+	p := repoPath(repo) + "/git/refs"
+	if prefix != "" {
+		p += "/" + strings.TrimSuffix(prefix, "/")
+	}
+	var refs []giteaRef
+	if err := g.doPage(ctx, p, &refs); err != nil {
+		// Gitea responds 404 if no ref is under prefix
+		if prefix != "" && strings.Contains(err.Error(), "404 Not Found") {
+			return nil, nil
+		}
+		return nil, errors.Trace(err)
+	}
+	var all []*github.Reference
+	for _, ref := range refs {
+		all = append(all, &github.Reference{
+			Ref: github.String(ref.Ref),
+			Object: &github.GitObject{
+				Type: github.String(ref.Object.Type),
+				SHA:  github.String(ref.Object.SHA),
+			},
+		})
+	}
+	return all, nil
+}
+
+// GetRef gets a ref exactly, returns ErrRefNotFound if it doesn't exist
+func (g *Gitea) GetRef(ctx context.Context, repo types.Repo, ref string) (*github.Reference, error) {
+	// Gitea lists refs start with ref
+	refs, err := g.ListRefs(ctx, repo, ref)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, r := range refs {
+		if r.GetRef() == "refs/"+strings.TrimPrefix(ref, "refs/") {
+			return r, nil
+		}
+	}
+	return nil, ErrRefNotFound
+}
+
+// RemoteURL composes git remote address with credential of user
+func (g *Gitea) RemoteURL(repo types.Repo, user string) string {
+	return remoteURL(g.gitURL, repo, user, g.token)
+}
+
+// doPage gets a page of a list with its own timeout
+func (g *Gitea) doPage(ctx context.Context, p string, out interface{}) error {
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
+	return errors.Trace(g.do(ctx, http.MethodGet, p, nil, out))
+}
+
+func (g *Gitea) do(ctx context.Context, method, p string, in, out interface{}) error {
+	u, err := g.baseURL.Parse(p)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var body []byte
+	if in != nil {
+		body, err = json.Marshal(in)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if g.token != "" {
+		req.Header.Set("Authorization", "token "+g.token)
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// keep the same shape with go-github errors, so callers can match status text
+		return errors.Errorf("%s %s: %d %s %s", method, u.String(),
+			resp.StatusCode, http.StatusText(resp.StatusCode), strings.TrimSpace(string(data)))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return errors.Trace(json.Unmarshal(data, out))
+}
+
+func repoPath(repo types.Repo) string {
+	return fmt.Sprintf("repos/%s/%s", url.PathEscape(repo.Owner), url.PathEscape(repo.Repo))
+}
+
+func (u giteaUser) toGithub() *github.User {
+	return &github.User{
+		Login: github.String(u.Login),
+		Email: github.String(u.Email),
+	}
+}
+
+func toGithubLabels(labels []giteaLabel) []*github.Label {
+	var ls []*github.Label
+	for _, label := range labels {
+		ls = append(ls, &github.Label{
+			Name:  github.String(label.Name),
+			Color: github.String(label.Color),
+		})
+	}
+	return ls
+}
+
+func (m *giteaMilestone) toGithub() *github.Milestone {
+	if m == nil {
+		return nil
+	}
+	milestone := github.Milestone{
+		// Gitea addresses milestones by id
+		ID:           github.Int64(m.ID),
+		Number:       github.Int(int(m.ID)),
+		Title:        github.String(m.Title),
+		Description:  github.String(m.Description),
+		State:        github.String(m.State),
+		OpenIssues:   github.Int(m.OpenIssues),
+		ClosedIssues: github.Int(m.ClosedIssues),
+	}
+	if m.Deadline != nil {
+		milestone.DueOn = m.Deadline
+	}
+	return &milestone
+}
+
+func (i *giteaIssue) toGithub() *github.Issue {
+	issue := github.Issue{
+		ID:        github.Int64(i.ID),
+		Number:    github.Int(i.Number),
+		Title:     github.String(i.Title),
+		Body:      github.String(i.Body),
+		State:     github.String(i.State),
+		HTMLURL:   github.String(i.HTMLURL),
+		User:      i.User.toGithub(),
+		Labels:    toGithubLabels(i.Labels),
+		Milestone: i.Milestone.toGithub(),
+	}
+	if i.PullRequest != nil {
+		issue.PullRequestLinks = &github.PullRequestLinks{
+			HTMLURL: github.String(i.HTMLURL),
+		}
+	}
+	return &issue
+}
+
+func (p *giteaPull) toGithub() *github.PullRequest {
+	return &github.PullRequest{
+		ID:             github.Int64(p.ID),
+		Number:         github.Int(p.Number),
+		Title:          github.String(p.Title),
+		Body:           github.String(p.Body),
+		State:          github.String(p.State),
+		HTMLURL:        github.String(p.HTMLURL),
+		User:           p.User.toGithub(),
+		Labels:         toGithubLabels(p.Labels),
+		Milestone:      p.Milestone.toGithub(),
+		Merged:         github.Bool(p.Merged),
+		MergedAt:       p.MergedAt,
+		MergeCommitSHA: github.String(p.MergeCommitSHA),
+		Base: &github.PullRequestBranch{
+			Ref: github.String(p.Base.Ref),
+			SHA: github.String(p.Base.SHA),
+		},
+		Head: &github.PullRequestBranch{
+			Ref: github.String(p.Head.Ref),
+			SHA: github.String(p.Head.SHA),
+		},
+	}
+}
+
+func (c *giteaContent) toGithub() *github.RepositoryContent {
+	content := github.RepositoryContent{
+		Name:    github.String(c.Name),
+		Path:    github.String(c.Path),
+		SHA:     github.String(c.SHA),
+		Type:    github.String(c.Type),
+		Size:    github.Int(c.Size),
+		HTMLURL: github.String(c.HTMLURL),
+	}
+	if c.Content != "" {
+		content.Content = github.String(c.Content)
+		content.Encoding = github.String(c.Encoding)
+	}
+	return &content
+}
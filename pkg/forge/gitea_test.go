@@ -0,0 +1,88 @@
+package forge
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/you06/releaser/pkg/types"
+)
+
+func TestGitea(t *testing.T) {
+	repo := types.Repo{Owner: "pingcap", Repo: "tics"}
+	mux := http.NewServeMux()
+	milestoneRequests := 0
+	mux.HandleFunc("/api/v1/repos/pingcap/tics/milestones", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("Authorization"), "token secret")
+		milestoneRequests++
+		if milestoneRequests == 1 {
+			// transient errors are retried like GitHub
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `[{"id": 7, "title": "v4.0.6", "state": "open"}]`)
+	})
+	mux.HandleFunc("/api/v1/repos/pingcap/tics/issues", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Query().Get("milestones"), "7")
+		fmt.Fprint(w, `[{"number": 1, "title": "issue"}, {"number": 2, "title": "pull", "pull_request": {"merged": true}}]`)
+	})
+	mux.HandleFunc("/api/v1/repos/pingcap/tics/pulls/2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"number": 2, "merged": true, "base": {"ref": "release-4.0"}, "labels": [{"name": "type/bug-fix"}]}`)
+	})
+	mux.HandleFunc("/api/v1/repos/pingcap/tics/contents/docs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"name": "4.0.6.md", "type": "file"}]`)
+	})
+	mux.HandleFunc("/api/v1/repos/pingcap/tics/contents/docs/4.0.6.md", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"name": "4.0.6.md", "type": "file", "encoding": "base64", "content": "%s"}`,
+			base64.StdEncoding.EncodeToString([]byte("# v4.0.6")))
+	})
+	mux.HandleFunc("/api/v1/repos/pingcap/tics/pulls", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	g, err := NewGitea(server.URL, "", "secret", "")
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	milestones, err := g.ListMilestones(ctx, repo, "all")
+	assert.Nil(t, err)
+	assert.Equal(t, len(milestones), 1)
+	assert.Equal(t, milestones[0].GetNumber(), 7)
+	assert.Equal(t, milestoneRequests, 2)
+
+	issues, err := g.ListMilestoneIssues(ctx, repo, milestones[0])
+	assert.Nil(t, err)
+	assert.Equal(t, len(issues), 2)
+	assert.False(t, issues[0].IsPullRequest())
+	assert.True(t, issues[1].IsPullRequest())
+
+	pull, err := g.GetPull(ctx, repo, 2)
+	assert.Nil(t, err)
+	assert.True(t, pull.GetMerged())
+	assert.Equal(t, pull.GetBase().GetRef(), "release-4.0")
+	assert.Equal(t, pull.Labels[0].GetName(), "type/bug-fix")
+
+	_, dir, err := g.GetContents(ctx, repo, "/docs", "")
+	assert.Nil(t, err)
+	assert.Equal(t, dir[0].GetName(), "4.0.6.md")
+	file, _, err := g.GetContents(ctx, repo, "/docs/4.0.6.md", "")
+	assert.Nil(t, err)
+	content, err := file.GetContent()
+	assert.Nil(t, err)
+	assert.Equal(t, content, "# v4.0.6")
+
+	_, err = g.GetPull(ctx, repo, 3)
+	assert.Contains(t, err.Error(), "404 Not Found")
+
+	_, err = g.CreatePull(ctx, repo, &github.NewPullRequest{})
+	assert.Equal(t, err, ErrPullExists)
+
+	assert.Equal(t, g.RemoteURL(repo, "bot"), server.URL[:7]+"bot:secret@"+server.URL[7:]+"/pingcap/tics.git")
+}
@@ -0,0 +1,220 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
+	"golang.org/x/oauth2"
+)
+
+const (
+	githubGitURL = "https://github.com"
+	perpage      = 100
+)
+
+// GitHub implements Forge by GitHub API v3
+type GitHub struct {
+	Client *github.Client
+	gitURL *url.URL
+	token  string
+}
+
+// NewGitHub creates GitHub forge, empty apiURL and gitURL mean github.com,
+// responses are cached in cacheDir unless it's empty
+func NewGitHub(apiURL, gitURL, token, cacheDir string) (*GitHub, error) {
+	// retry on rate limits beneath oauth2 so that retried requests carry the token
+	transport := newTransport(cacheDir)
+	if token != "" {
+		transport = &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}),
+			Base:   transport,
+		}
+	}
+	httpClient := &http.Client{Transport: transport}
+	return NewGitHubWithClient(apiURL, gitURL, token, httpClient)
+}
+
+// NewGitHubWithClient creates GitHub forge with a custom http client
+func NewGitHubWithClient(apiURL, gitURL, token string, httpClient *http.Client) (*GitHub, error) {
+	client := github.NewClient(httpClient)
+	if apiURL != "" {
+		baseURL, err := url.Parse(apiURL)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if !strings.HasSuffix(baseURL.Path, "/") {
+			baseURL.Path += "/"
+		}
+		client.BaseURL = baseURL
+	}
+	if gitURL == "" {
+		gitURL = githubGitURL
+	}
+	u, err := url.Parse(gitURL)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &GitHub{
+		Client: client,
+		gitURL: u,
+		token:  token,
+	}, nil
+}
+
+// GetUser gets the authenticated user
+func (g *GitHub) GetUser(ctx context.Context) (*github.User, error) {
+	user, _, err := g.Client.Users.Get(ctx, "")
+	return user, errors.Trace(err)
+}
+
+// GetRepo gets repository info
+func (g *GitHub) GetRepo(ctx context.Context, repo types.Repo) (*github.Repository, error) {
+	r, _, err := g.Client.Repositories.Get(ctx, repo.Owner, repo.Repo)
+	return r, errors.Trace(err)
+}
+
+// CreateFork forks repo into the authenticated user's namespace
+func (g *GitHub) CreateFork(ctx context.Context, repo types.Repo) error {
+	_, _, err := g.Client.Repositories.CreateFork(ctx, repo.Owner, repo.Repo, &github.RepositoryCreateForkOptions{})
+	if _, ok := err.(*github.AcceptedError); ok {
+		// fork happens asynchronously
+		return nil
+	}
+	return errors.Trace(err)
+}
+
+// ListMilestones lists all milestones in given state
+func (g *GitHub) ListMilestones(ctx context.Context, repo types.Repo, state string) ([]*github.Milestone, error) {
+	var (
+		page  = 0
+		all   []*github.Milestone
+		batch []*github.Milestone
+		err   error
+	)
+	for page == 0 || len(batch) == perpage {
+		page++
+		pageCtx, cancel := utils.NewTimeoutContext(ctx)
+		batch, _, err = g.Client.Issues.ListMilestones(pageCtx, repo.Owner, repo.Repo, &github.MilestoneListOptions{
+			State: state,
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: perpage,
+			},
+		})
+		cancel()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		all = append(all, batch...)
+	}
+	return all, nil
+}
+
+// ListMilestoneIssues lists all issues and pulls in a milestone
+func (g *GitHub) ListMilestoneIssues(ctx context.Context, repo types.Repo, milestone *github.Milestone) ([]*github.Issue, error) {
+	var (
+		page  = 0
+		all   []*github.Issue
+		batch []*github.Issue
+		err   error
+	)
+	for page == 0 || len(batch) == perpage {
+		page++
+		pageCtx, cancel := utils.NewTimeoutContext(ctx)
+		batch, _, err = g.Client.Issues.ListByRepo(pageCtx, repo.Owner, repo.Repo, &github.IssueListByRepoOptions{
+			Milestone: fmt.Sprintf("%d", milestone.GetNumber()),
+			State:     "all",
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: perpage,
+			},
+		})
+		cancel()
+		if err != nil {
+			return all, errors.Trace(err)
+		}
+		all = append(all, batch...)
+	}
+	return all, nil
+}
+
+// GetPull gets a pull request by number
+func (g *GitHub) GetPull(ctx context.Context, repo types.Repo, number int) (*github.PullRequest, error) {
+	pull, _, err := g.Client.PullRequests.Get(ctx, repo.Owner, repo.Repo, number)
+	return pull, errors.Trace(err)
+}
+
+// CreatePull opens a pull request
+func (g *GitHub) CreatePull(ctx context.Context, repo types.Repo, newPull *github.NewPullRequest) (*github.PullRequest, error) {
+	pull, _, err := g.Client.PullRequests.Create(ctx, repo.Owner, repo.Repo, newPull)
+	if err != nil && strings.Contains(err.Error(), "A pull request already exists") {
+		return nil, ErrPullExists
+	}
+	return pull, errors.Trace(err)
+}
+
+// GetContents gets a file or lists a directory at ref
+func (g *GitHub) GetContents(ctx context.Context, repo types.Repo, path, ref string) (*github.RepositoryContent, []*github.RepositoryContent, error) {
+	file, dir, _, err := g.Client.Repositories.GetContents(ctx, repo.Owner, repo.Repo, path,
+		&github.RepositoryContentGetOptions{
+			Ref: ref,
+		})
+	return file, dir, errors.Trace(err)
+}
+
+// ListRefs lists refs under prefix, or all refs if prefix is empty
+func (g *GitHub) ListRefs(ctx context.Context, repo types.Repo, prefix string) ([]*github.Reference, error) {
+	var (
+		page  = 0
+		all   []*github.Reference
+		batch []*github.Reference
+		err   error
+	)
+	for page == 0 || len(batch) == perpage {
+		page++
+		pageCtx, cancel := utils.NewTimeoutContext(ctx)
+		var resp *github.Response
+		batch, resp, err = g.Client.Git.ListRefs(pageCtx, repo.Owner, repo.Repo, &github.ReferenceListOptions{
+			Type: strings.TrimSuffix(prefix, "/"),
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: perpage,
+			},
+		})
+		cancel()
+		// GitHub responds 404 if no ref is under prefix
+		if prefix != "" && resp != nil && resp.StatusCode == http.StatusNotFound {
+			return all, nil
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		all = append(all, batch...)
+	}
+	return all, nil
+}
+
+// GetRef gets a ref exactly, returns ErrRefNotFound if it doesn't exist
+func (g *GitHub) GetRef(ctx context.Context, repo types.Repo, ref string) (*github.Reference, error) {
+	r, resp, err := g.Client.Git.GetRef(ctx, repo.Owner, repo.Repo, ref)
+	if err != nil {
+		// GitHub responds refs start with ref if there is not an exact match, or 404 if there is none
+		if resp != nil && (resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotFound) {
+			return nil, ErrRefNotFound
+		}
+		return nil, errors.Trace(err)
+	}
+	return r, nil
+}
+
+// RemoteURL composes git remote address with credential of user
+func (g *GitHub) RemoteURL(repo types.Repo, user string) string {
+	return remoteURL(g.gitURL, repo, user, g.token)
+}
@@ -0,0 +1,35 @@
+package forge
+
+import (
+	"context"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/you06/releaser/pkg/types"
+)
+
+// CommitComparer lists commits between two refs, it's an optional capability of Forge
+type CommitComparer interface {
+	// CompareCommits compares base and head, the commits may be truncated, see TotalCommits
+	CompareCommits(ctx context.Context, repo types.Repo, base, head string) (*github.CommitsComparison, error)
+}
+
+// CommitPullsLister finds pulls of commits, it's an optional capability of Forge
+type CommitPullsLister interface {
+	// ListCommitPulls lists pulls which contain the commit
+	ListCommitPulls(ctx context.Context, repo types.Repo, sha string) ([]*github.PullRequest, error)
+}
+
+// CompareCommits compares base and head, GitHub returns at most 250 commits
+func (g *GitHub) CompareCommits(ctx context.Context, repo types.Repo, base, head string) (*github.CommitsComparison, error) {
+	comparison, _, err := g.Client.Repositories.CompareCommits(ctx, repo.Owner, repo.Repo, base, head)
+	return comparison, errors.Trace(err)
+}
+
+// ListCommitPulls lists pulls which contain the commit
+func (g *GitHub) ListCommitPulls(ctx context.Context, repo types.Repo, sha string) ([]*github.PullRequest, error) {
+	pulls, _, err := g.Client.PullRequests.ListPullRequestsWithCommit(ctx, repo.Owner, repo.Repo, sha, &github.PullRequestListOptions{
+		State: "all",
+	})
+	return pulls, errors.Trace(err)
+}
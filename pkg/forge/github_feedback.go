@@ -0,0 +1,119 @@
+package forge
+
+import (
+	"context"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
+)
+
+// PullCommenter manages comments of pulls, it's an optional capability of Forge
+type PullCommenter interface {
+	// ListPullComments lists all comments of a pull
+	ListPullComments(ctx context.Context, repo types.Repo, number int) ([]*github.IssueComment, error)
+	// CreatePullComment comments on a pull
+	CreatePullComment(ctx context.Context, repo types.Repo, number int, body string) (*github.IssueComment, error)
+	// EditPullComment replaces body of a comment
+	EditPullComment(ctx context.Context, repo types.Repo, id int64, body string) error
+	// DeletePullComment deletes a comment
+	DeletePullComment(ctx context.Context, repo types.Repo, id int64) error
+}
+
+// CheckRunner manages check runs of commits, it's an optional capability of Forge
+type CheckRunner interface {
+	// ListCheckRuns lists check runs of ref by name
+	ListCheckRuns(ctx context.Context, repo types.Repo, ref, name string) ([]*github.CheckRun, error)
+	// CreateCheckRun creates a check run
+	CreateCheckRun(ctx context.Context, repo types.Repo, opts github.CreateCheckRunOptions) (*github.CheckRun, error)
+	// UpdateCheckRun updates a check run
+	UpdateCheckRun(ctx context.Context, repo types.Repo, id int64, opts github.UpdateCheckRunOptions) error
+}
+
+// ListPullComments lists all comments of a pull
+func (g *GitHub) ListPullComments(ctx context.Context, repo types.Repo, number int) ([]*github.IssueComment, error) {
+	var (
+		page  = 0
+		all   []*github.IssueComment
+		batch []*github.IssueComment
+		err   error
+	)
+	for page == 0 || len(batch) == perpage {
+		page++
+		pageCtx, cancel := utils.NewTimeoutContext(ctx)
+		batch, _, err = g.Client.Issues.ListComments(pageCtx, repo.Owner, repo.Repo, number, &github.IssueListCommentsOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: perpage,
+			},
+		})
+		cancel()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		all = append(all, batch...)
+	}
+	return all, nil
+}
+
+// CreatePullComment comments on a pull
+func (g *GitHub) CreatePullComment(ctx context.Context, repo types.Repo, number int, body string) (*github.IssueComment, error) {
+	comment, _, err := g.Client.Issues.CreateComment(ctx, repo.Owner, repo.Repo, number, &github.IssueComment{
+		Body: github.String(body),
+	})
+	return comment, errors.Trace(err)
+}
+
+// EditPullComment replaces body of a comment
+func (g *GitHub) EditPullComment(ctx context.Context, repo types.Repo, id int64, body string) error {
+	_, _, err := g.Client.Issues.EditComment(ctx, repo.Owner, repo.Repo, id, &github.IssueComment{
+		Body: github.String(body),
+	})
+	return errors.Trace(err)
+}
+
+// DeletePullComment deletes a comment
+func (g *GitHub) DeletePullComment(ctx context.Context, repo types.Repo, id int64) error {
+	_, err := g.Client.Issues.DeleteComment(ctx, repo.Owner, repo.Repo, id)
+	return errors.Trace(err)
+}
+
+// ListCheckRuns lists check runs of ref by name
+func (g *GitHub) ListCheckRuns(ctx context.Context, repo types.Repo, ref, name string) ([]*github.CheckRun, error) {
+	var (
+		page = 0
+		all  []*github.CheckRun
+		res  *github.ListCheckRunsResults
+		err  error
+	)
+	for page == 0 || len(res.CheckRuns) == perpage {
+		page++
+		pageCtx, cancel := utils.NewTimeoutContext(ctx)
+		res, _, err = g.Client.Checks.ListCheckRunsForRef(pageCtx, repo.Owner, repo.Repo, ref, &github.ListCheckRunsOptions{
+			CheckName: github.String(name),
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: perpage,
+			},
+		})
+		cancel()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		all = append(all, res.CheckRuns...)
+	}
+	return all, nil
+}
+
+// CreateCheckRun creates a check run
+func (g *GitHub) CreateCheckRun(ctx context.Context, repo types.Repo, opts github.CreateCheckRunOptions) (*github.CheckRun, error) {
+	run, _, err := g.Client.Checks.CreateCheckRun(ctx, repo.Owner, repo.Repo, opts)
+	return run, errors.Trace(err)
+}
+
+// UpdateCheckRun updates a check run
+func (g *GitHub) UpdateCheckRun(ctx context.Context, repo types.Repo, id int64, opts github.UpdateCheckRunOptions) error {
+	_, _, err := g.Client.Checks.UpdateCheckRun(ctx, repo.Owner, repo.Repo, id, opts)
+	return errors.Trace(err)
+}
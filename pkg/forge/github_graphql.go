@@ -0,0 +1,196 @@
+package forge
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
+)
+
+// graphqlPath is relative to API v3 base url,
+// it's /graphql on api.github.com and /api/graphql on GitHub Enterprise
+const graphqlPath = "../graphql"
+
+const milestonePullsQuery = `query($owner: String!, $name: String!, $number: Int!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    milestone(number: $number) {
+      pullRequests(first: 100, after: $cursor) {
+        pageInfo {
+          hasNextPage
+          endCursor
+        }
+        nodes {
+          number
+          title
+          body
+          url
+          state
+          merged
+          mergedAt
+          baseRefName
+          headRefOid
+          mergeCommit {
+            oid
+          }
+          author {
+            login
+          }
+          labels(first: 100) {
+            nodes {
+              name
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+// MilestonePullsLister lists all pulls in a milestone in bulk,
+// it's an optional capability of Forge
+type MilestonePullsLister interface {
+	ListMilestonePulls(ctx context.Context, repo types.Repo, milestone *github.Milestone) ([]*github.PullRequest, error)
+}
+
+type graphqlRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphqlError struct {
+	Message string `json:"message"`
+}
+
+type graphqlPull struct {
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Body        string     `json:"body"`
+	URL         string     `json:"url"`
+	State       string     `json:"state"`
+	Merged      bool       `json:"merged"`
+	MergedAt    *time.Time `json:"mergedAt"`
+	BaseRefName string     `json:"baseRefName"`
+	HeadRefOID  string     `json:"headRefOid"`
+	MergeCommit *struct {
+		OID string `json:"oid"`
+	} `json:"mergeCommit"`
+	Author *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+}
+
+type milestonePullsResponse struct {
+	Data struct {
+		Repository *struct {
+			Milestone *struct {
+				PullRequests struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []graphqlPull `json:"nodes"`
+				} `json:"pullRequests"`
+			} `json:"milestone"`
+		} `json:"repository"`
+	} `json:"data"`
+	Errors []graphqlError `json:"errors"`
+}
+
+// ListMilestonePulls lists all pulls in a milestone by GitHub API v4 in paginated batches
+func (g *GitHub) ListMilestonePulls(ctx context.Context, repo types.Repo, milestone *github.Milestone) ([]*github.PullRequest, error) {
+	var (
+		all    []*github.PullRequest
+		cursor *string
+	)
+	for {
+		var resp milestonePullsResponse
+		pageCtx, cancel := utils.NewTimeoutContext(ctx)
+		err := g.graphql(pageCtx, milestonePullsQuery, map[string]interface{}{
+			"owner":  repo.Owner,
+			"name":   repo.Repo,
+			"number": milestone.GetNumber(),
+			"cursor": cursor,
+		}, &resp)
+		cancel()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(resp.Errors) > 0 {
+			return nil, errors.Errorf("graphql error: %s", joinGraphqlErrors(resp.Errors))
+		}
+		if resp.Data.Repository == nil || resp.Data.Repository.Milestone == nil {
+			return nil, errors.Errorf("milestone %d not found in %s", milestone.GetNumber(), repo)
+		}
+		pulls := resp.Data.Repository.Milestone.PullRequests
+		for i := range pulls.Nodes {
+			all = append(all, pulls.Nodes[i].toGithub(milestone))
+		}
+		if !pulls.PageInfo.HasNextPage {
+			return all, nil
+		}
+		endCursor := pulls.PageInfo.EndCursor
+		cursor = &endCursor
+	}
+}
+
+func (g *GitHub) graphql(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error {
+	req, err := g.Client.NewRequest("POST", graphqlPath, &graphqlRequest{
+		Query:     query,
+		Variables: variables,
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = g.Client.Do(ctx, req, v)
+	return errors.Trace(err)
+}
+
+func joinGraphqlErrors(errs []graphqlError) string {
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (p *graphqlPull) toGithub(milestone *github.Milestone) *github.PullRequest {
+	pull := github.PullRequest{
+		Number:    github.Int(p.Number),
+		Title:     github.String(p.Title),
+		Body:      github.String(p.Body),
+		HTMLURL:   github.String(p.URL),
+		State:     github.String(strings.ToLower(p.State)),
+		Merged:    github.Bool(p.Merged),
+		MergedAt:  p.MergedAt,
+		Milestone: milestone,
+		Base: &github.PullRequestBranch{
+			Ref: github.String(p.BaseRefName),
+		},
+		Head: &github.PullRequestBranch{
+			SHA: github.String(p.HeadRefOID),
+		},
+	}
+	// GitHub API v3 reports merged pulls as closed
+	if pull.GetState() == "merged" {
+		pull.State = github.String("closed")
+	}
+	if p.MergeCommit != nil {
+		pull.MergeCommitSHA = github.String(p.MergeCommit.OID)
+	}
+	if p.Author != nil {
+		pull.User = &github.User{Login: github.String(p.Author.Login)}
+	}
+	for _, label := range p.Labels.Nodes {
+		pull.Labels = append(pull.Labels, &github.Label{Name: github.String(label.Name)})
+	}
+	return &pull
+}
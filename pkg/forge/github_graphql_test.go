@@ -0,0 +1,50 @@
+package forge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/you06/releaser/pkg/githubtest"
+	"github.com/you06/releaser/pkg/types"
+)
+
+func TestListMilestonePulls(t *testing.T) {
+	repo := types.Repo{Owner: "pingcap", Repo: "tidb"}
+	server := githubtest.NewServer()
+	defer server.Close()
+
+	milestone := server.AddMilestone(repo, "v4.0.6", "open")
+	other := server.AddMilestone(repo, "v4.0.7", "open")
+	for i := 1; i <= 150; i++ {
+		server.AddPull(repo, &github.PullRequest{
+			Number:         github.Int(i),
+			Merged:         github.Bool(true),
+			MergeCommitSHA: github.String("sha"),
+			Milestone:      milestone,
+			Base:           &github.PullRequestBranch{Ref: github.String("release-4.0")},
+			User:           &github.User{Login: github.String("author")},
+			Labels:         []*github.Label{{Name: github.String("type/bug-fix")}},
+		})
+	}
+	server.AddPull(repo, &github.PullRequest{Number: github.Int(151), Milestone: other})
+
+	g, err := NewGitHub(server.APIURL(), "", "", "")
+	assert.Nil(t, err)
+	pulls, err := g.ListMilestonePulls(context.Background(), repo, milestone)
+	assert.Nil(t, err)
+	assert.Equal(t, len(pulls), 150)
+	assert.Equal(t, server.CountRequests("POST /graphql"), 2, "paginated")
+	assert.Equal(t, pulls[149].GetNumber(), 150)
+	assert.True(t, pulls[0].GetMerged())
+	assert.Equal(t, pulls[0].GetState(), "closed")
+	assert.Equal(t, pulls[0].GetBase().GetRef(), "release-4.0")
+	assert.Equal(t, pulls[0].GetMergeCommitSHA(), "sha")
+	assert.Equal(t, pulls[0].GetUser().GetLogin(), "author")
+	assert.Equal(t, pulls[0].Labels[0].GetName(), "type/bug-fix")
+
+	server.DisableGraphQL = true
+	_, err = g.ListMilestonePulls(context.Background(), repo, milestone)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,31 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/juju/errors"
+	"github.com/you06/releaser/pkg/types"
+)
+
+// MilestoneSetter changes milestones of pulls, it's an optional capability of Forge
+type MilestoneSetter interface {
+	// SetPullMilestone sets milestone of a pull by milestone number, 0 removes the milestone
+	SetPullMilestone(ctx context.Context, repo types.Repo, number, milestone int) error
+}
+
+// SetPullMilestone sets milestone of a pull by milestone number, 0 removes the milestone
+func (g *GitHub) SetPullMilestone(ctx context.Context, repo types.Repo, number, milestone int) error {
+	// IssueRequest omits empty milestone, so null is sent by a raw request to remove it
+	var value interface{}
+	if milestone != 0 {
+		value = milestone
+	}
+	req, err := g.Client.NewRequest("PATCH", fmt.Sprintf("repos/%s/%s/issues/%d", repo.Owner, repo.Repo, number),
+		map[string]interface{}{"milestone": value})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = g.Client.Do(ctx, req, nil)
+	return errors.Trace(err)
+}
@@ -1,6 +1,7 @@
 package git
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -11,46 +12,45 @@ import (
 	"github.com/google/go-github/v30/github"
 	"github.com/juju/errors"
 	"github.com/you06/releaser/config"
+	"github.com/you06/releaser/pkg/forge"
 	"github.com/you06/releaser/pkg/types"
 	"github.com/you06/releaser/pkg/utils"
 )
 
 // Git ...
 type Git struct {
-	Github      *github.Client
-	User        *github.User
-	BaseDir     string
-	Dir         string
-	BaseRepo    types.Repo
-	HeadRepo    types.Repo
-	GithubToken string
+	Forge    forge.Forge
+	User     *github.User
+	BaseDir  string
+	Dir      string
+	BaseRepo types.Repo
+	HeadRepo types.Repo
 }
 
 // Config ...
 type Config struct {
-	Github *github.Client
-	User   *github.User
-	Base   types.Repo
-	Head   types.Repo
-	Dir    string
+	Forge forge.Forge
+	User  *github.User
+	Base  types.Repo
+	Head  types.Repo
+	Dir   string
 }
 
 // New creates Git instance
 func New(cfg *config.Config, gitCfg *Config) *Git {
 	return &Git{
-		Github:      gitCfg.Github,
-		User:        gitCfg.User,
-		BaseDir:     cfg.GitDir,
-		Dir:         gitCfg.Dir,
-		BaseRepo:    gitCfg.Base,
-		HeadRepo:    gitCfg.Head,
-		GithubToken: cfg.GithubToken,
+		Forge:    gitCfg.Forge,
+		User:     gitCfg.User,
+		BaseDir:  cfg.GitDir,
+		Dir:      gitCfg.Dir,
+		BaseRepo: gitCfg.Base,
+		HeadRepo: gitCfg.Head,
 	}
 }
 
 // Clone repo
 func (g *Git) Clone() error {
-	baseHTTPSaddr := g.BaseRepo.ComposeHTTPSWithCredential(g.User.GetLogin(), g.GithubToken)
+	baseHTTPSaddr := g.Forge.RemoteURL(g.BaseRepo, g.User.GetLogin())
 	dir := path.Join(g.BaseDir, g.Dir)
 	fmt.Println(dir)
 	_, err := do(g.BaseDir, "git", "clone", baseHTTPSaddr, dir)
@@ -116,8 +116,7 @@ func (g *Git) Commit(message string) error {
 func (g *Git) Push(branch string) error {
 	var (
 		dir      = path.Join(g.BaseDir, g.Dir)
-		baseRepo = fmt.Sprintf("https://%s:%s@github.com/%s/%s.git",
-			g.User.GetLogin(), g.GithubToken, g.HeadRepo.Owner, g.HeadRepo.Repo)
+		baseRepo = g.Forge.RemoteURL(g.HeadRepo, g.User.GetLogin())
 	)
 	_, err := do(dir, "git", "push", baseRepo, branch, "--force")
 	return errors.Trace(err)
@@ -139,6 +138,32 @@ func (g *Git) CheckTagSHA(tag string) (string, error) {
 	return strings.Trim(sha, " "), nil
 }
 
+// LogRange lists commits reachable from to but not from, only subjects of messages are read.
+// Branches are read from origin, since a fresh clone only has the default branch locally.
+func (g *Git) LogRange(from, to string) ([]*github.RepositoryCommit, error) {
+	dir := path.Join(g.BaseDir, g.Dir)
+	out, err := do(dir, "git", "log", "--format=%H%x1f%P%x1f%s%x1e", localRef(dir, from)+".."+localRef(dir, to))
+	if err != nil {
+		return nil, errors.Errorf("git log %s..%s failed, %s", from, to, strings.TrimSpace(out))
+	}
+	var commits []*github.RepositoryCommit
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		if len(fields) != 3 {
+			continue
+		}
+		commit := github.RepositoryCommit{
+			SHA:    github.String(fields[0]),
+			Commit: &github.Commit{Message: github.String(fields[2])},
+		}
+		for _, parent := range strings.Fields(fields[1]) {
+			commit.Parents = append(commit.Parents, &github.Commit{SHA: github.String(parent)})
+		}
+		commits = append(commits, &commit)
+	}
+	return commits, nil
+}
+
 // Clear delete cloned repo
 func (g *Git) Clear() error {
 	dir := path.Join(g.BaseDir, g.Dir)
@@ -146,26 +171,37 @@ func (g *Git) Clear() error {
 	return errors.Trace(err)
 }
 
-// CreatePull creates pull request
-func (g *Git) CreatePull(title, branch string) (*github.PullRequest, error) {
+// CreatePull creates pull request, title is used as body if body is empty
+func (g *Git) CreatePull(ctx context.Context, title, body, branch string) (*github.PullRequest, error) {
+	if body == "" {
+		body = title
+	}
 	newPull := github.NewPullRequest{
 		Title:               github.String(title),
 		Head:                github.String(fmt.Sprintf("%s:%s", g.HeadRepo.Owner, branch)),
 		Base:                github.String("master"),
-		Body:                github.String(title),
+		Body:                github.String(body),
 		MaintainerCanModify: github.Bool(true),
 		Draft:               github.Bool(false),
 	}
-	ctx, _ := utils.NewTimeoutContext()
-	pull, _, err := g.Github.PullRequests.Create(ctx,
-		g.BaseRepo.Owner, g.BaseRepo.Repo, &newPull)
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
+	pull, err := g.Forge.CreatePull(ctx, g.BaseRepo, &newPull)
 
-	if err != nil && strings.Contains(err.Error(), "A pull request already exists") {
+	if errors.Cause(err) == forge.ErrPullExists {
 		return nil, nil
 	}
 	return pull, errors.Trace(err)
 }
 
+// localRef gets the remote-tracking branch of ref if there is, or ref itself like tags and commits
+func localRef(dir, ref string) string {
+	if _, err := do(dir, "git", "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+ref); err == nil {
+		return "origin/" + ref
+	}
+	return ref
+}
+
 func do(dir string, c string, args ...string) (string, error) {
 	cmd := exec.Command(c, args...)
 	cmd.Dir = dir
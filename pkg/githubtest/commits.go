@@ -0,0 +1,64 @@
+package githubtest
+
+import (
+	"net/http"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/you06/releaser/pkg/types"
+)
+
+// comparison is the compare result between two refs
+type comparison struct {
+	commits []*github.RepositoryCommit
+	total   int
+}
+
+// AddComparison sets commits between base and head, total larger than len(commits) means truncated
+func (s *Server) AddComparison(repo types.Repo, base, head string, total int, commits ...*github.RepositoryCommit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repo(repo)
+	if r.comparisons == nil {
+		r.comparisons = make(map[string]comparison)
+	}
+	r.comparisons[base+"..."+head] = comparison{commits: commits, total: total}
+}
+
+// SetCommitPulls sets pulls which contain a commit
+func (s *Server) SetCommitPulls(repo types.Repo, sha string, pulls ...*github.PullRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repo(repo)
+	if r.commitPulls == nil {
+		r.commitPulls = make(map[string][]*github.PullRequest)
+	}
+	r.commitPulls[sha] = pulls
+}
+
+// Commit makes a commit with message
+func Commit(sha, message string) *github.RepositoryCommit {
+	return &github.RepositoryCommit{
+		SHA:    github.String(sha),
+		Commit: &github.Commit{Message: github.String(message)},
+	}
+}
+
+func (s *Server) compare(w http.ResponseWriter, fixture *repoFixture, basehead string) {
+	c, ok := fixture.comparisons[basehead]
+	if !ok {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, &github.CommitsComparison{
+		TotalCommits: github.Int(c.total),
+		Commits:      c.commits,
+	})
+}
+
+func (s *Server) listCommitPulls(w http.ResponseWriter, fixture *repoFixture, sha string) {
+	pulls, ok := fixture.commitPulls[sha]
+	if !ok {
+		pulls = []*github.PullRequest{}
+	}
+	writeJSON(w, http.StatusOK, pulls)
+}
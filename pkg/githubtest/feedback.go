@@ -0,0 +1,155 @@
+package githubtest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/you06/releaser/pkg/types"
+)
+
+type pullComment struct {
+	number  int
+	comment *github.IssueComment
+}
+
+// PullComments returns comments of a pull
+func (s *Server) PullComments(repo types.Repo, number int) []*github.IssueComment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var comments []*github.IssueComment
+	for _, c := range s.repo(repo).comments {
+		if c.number == number {
+			comments = append(comments, c.comment)
+		}
+	}
+	return comments
+}
+
+// CheckRuns returns check runs of a commit
+func (s *Server) CheckRuns(repo types.Repo, sha string) []*github.CheckRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var runs []*github.CheckRun
+	for _, run := range s.repo(repo).checkRuns {
+		if run.GetHeadSHA() == sha {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+func (s *Server) nextID() int64 {
+	s.lastID++
+	return s.lastID
+}
+
+// pullComments lists or creates comments of a pull
+func (s *Server) pullComments(w http.ResponseWriter, r *http.Request, fixture *repoFixture, number int) {
+	switch r.Method {
+	case http.MethodGet:
+		var comments []*github.IssueComment
+		for _, c := range fixture.comments {
+			if c.number == number {
+				comments = append(comments, c.comment)
+			}
+		}
+		writeJSON(w, http.StatusOK, paginate(r, len(comments), func(i int) interface{} { return comments[i] }))
+	case http.MethodPost:
+		var comment github.IssueComment
+		if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+		comment.ID = github.Int64(s.nextID())
+		comment.User = s.user
+		fixture.comments = append(fixture.comments, &pullComment{number: number, comment: &comment})
+		writeJSON(w, http.StatusCreated, &comment)
+	default:
+		notFound(w)
+	}
+}
+
+// pullComment edits or deletes a comment
+func (s *Server) pullComment(w http.ResponseWriter, r *http.Request, fixture *repoFixture, id int64) {
+	for i, c := range fixture.comments {
+		if c.comment.GetID() != id {
+			continue
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			var comment github.IssueComment
+			if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+				return
+			}
+			c.comment.Body = comment.Body
+			writeJSON(w, http.StatusOK, c.comment)
+		case http.MethodDelete:
+			fixture.comments = append(fixture.comments[:i], fixture.comments[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			notFound(w)
+		}
+		return
+	}
+	notFound(w)
+}
+
+func (s *Server) listCheckRuns(w http.ResponseWriter, r *http.Request, fixture *repoFixture, ref string) {
+	name := r.URL.Query().Get("check_name")
+	runs := []*github.CheckRun{}
+	for _, run := range fixture.checkRuns {
+		if run.GetHeadSHA() == ref && (name == "" || run.GetName() == name) {
+			runs = append(runs, run)
+		}
+	}
+	writeJSON(w, http.StatusOK, &github.ListCheckRunsResults{
+		Total:     github.Int(len(runs)),
+		CheckRuns: runs,
+	})
+}
+
+func (s *Server) createCheckRun(w http.ResponseWriter, r *http.Request, fixture *repoFixture) {
+	if s.ForbidCheckRuns {
+		writeJSON(w, http.StatusForbidden, map[string]string{"message": "Resource not accessible by personal access token"})
+		return
+	}
+	var opts github.CreateCheckRunOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	run := github.CheckRun{
+		ID:         github.Int64(s.nextID()),
+		Name:       github.String(opts.Name),
+		HeadSHA:    github.String(opts.HeadSHA),
+		Status:     opts.Status,
+		Conclusion: opts.Conclusion,
+		Output:     opts.Output,
+	}
+	fixture.checkRuns = append(fixture.checkRuns, &run)
+	writeJSON(w, http.StatusCreated, &run)
+}
+
+func (s *Server) updateCheckRun(w http.ResponseWriter, r *http.Request, fixture *repoFixture, id int64) {
+	if s.ForbidCheckRuns {
+		writeJSON(w, http.StatusForbidden, map[string]string{"message": "Resource not accessible by personal access token"})
+		return
+	}
+	var opts github.UpdateCheckRunOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	for _, run := range fixture.checkRuns {
+		if run.GetID() == id {
+			run.Status = opts.Status
+			run.Conclusion = opts.Conclusion
+			run.Output = opts.Output
+			writeJSON(w, http.StatusOK, run)
+			return
+		}
+	}
+	notFound(w)
+}
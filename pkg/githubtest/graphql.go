@@ -0,0 +1,119 @@
+package githubtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/you06/releaser/pkg/types"
+)
+
+const graphqlPageSize = 100
+
+type graphqlRequest struct {
+	Query     string          `json:"query"`
+	Variables graphqlVariable `json:"variables"`
+}
+
+type graphqlVariable struct {
+	Owner  string  `json:"owner"`
+	Name   string  `json:"name"`
+	Number int     `json:"number"`
+	Cursor *string `json:"cursor"`
+}
+
+// graphql serves the queries used by releaser
+func (s *Server) graphql(w http.ResponseWriter, r *http.Request) {
+	if s.DisableGraphQL {
+		notFound(w)
+		return
+	}
+	var req graphqlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeGraphqlError(w, err.Error())
+		return
+	}
+	if !strings.Contains(req.Query, "pullRequests") {
+		writeGraphqlError(w, "unsupported query")
+		return
+	}
+	s.milestonePulls(w, req.Variables)
+}
+
+func (s *Server) milestonePulls(w http.ResponseWriter, v graphqlVariable) {
+	fixture, ok := s.repos[types.Repo{Owner: v.Owner, Repo: v.Name}]
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"repository": nil}})
+		return
+	}
+	var pulls []*github.PullRequest
+	for _, pull := range fixture.pulls {
+		if pull.GetMilestone().GetNumber() == v.Number {
+			pulls = append(pulls, pull)
+		}
+	}
+
+	start := 0
+	if v.Cursor != nil {
+		start, _ = strconv.Atoi(*v.Cursor)
+	}
+	end := start + graphqlPageSize
+	if end > len(pulls) {
+		end = len(pulls)
+	}
+	var nodes []map[string]interface{}
+	for _, pull := range pulls[start:end] {
+		nodes = append(nodes, pullNode(pull))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"repository": map[string]interface{}{
+				"milestone": map[string]interface{}{
+					"pullRequests": map[string]interface{}{
+						"pageInfo": map[string]interface{}{
+							"hasNextPage": end < len(pulls),
+							"endCursor":   strconv.Itoa(end),
+						},
+						"nodes": nodes,
+					},
+				},
+			},
+		},
+	})
+}
+
+func pullNode(pull *github.PullRequest) map[string]interface{} {
+	var labels []map[string]string
+	for _, label := range pull.Labels {
+		labels = append(labels, map[string]string{"name": label.GetName()})
+	}
+	state := strings.ToUpper(pull.GetState())
+	if pull.GetMerged() {
+		state = "MERGED"
+	}
+	node := map[string]interface{}{
+		"number":      pull.GetNumber(),
+		"title":       pull.GetTitle(),
+		"body":        pull.GetBody(),
+		"url":         pull.GetHTMLURL(),
+		"state":       state,
+		"merged":      pull.GetMerged(),
+		"mergedAt":    pull.MergedAt,
+		"baseRefName": pull.GetBase().GetRef(),
+		"headRefOid":  pull.GetHead().GetSHA(),
+		"author":      map[string]string{"login": pull.GetUser().GetLogin()},
+		"labels":      map[string]interface{}{"nodes": labels},
+	}
+	if pull.MergeCommitSHA != nil {
+		node["mergeCommit"] = map[string]string{"oid": pull.GetMergeCommitSHA()}
+	}
+	return node
+}
+
+func writeGraphqlError(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"errors": []map[string]string{{"message": message}},
+	})
+}
@@ -0,0 +1,458 @@
+package githubtest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/you06/releaser/pkg/types"
+)
+
+const defaultPerPage = 30
+
+// Server is an in-process fake GitHub API v3 server with fixtures
+type Server struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	user  *github.User
+	repos map[types.Repo]*repoFixture
+	// CreatedPulls records pulls opened through API
+	CreatedPulls []CreatedPull
+	// Forks records repos forked through API
+	Forks []types.Repo
+	// Requests records all requests in "METHOD /path" format
+	Requests []string
+	// DisableGraphQL makes GraphQL API unavailable
+	DisableGraphQL bool
+	// ForbidCheckRuns rejects writing check runs like GitHub does for personal access tokens
+	ForbidCheckRuns bool
+	// lastID is the last ID assigned to comments and check runs
+	lastID int64
+}
+
+// CreatedPull is a pull request opened through API
+type CreatedPull struct {
+	Repo types.Repo
+	Pull github.NewPullRequest
+}
+
+type repoFixture struct {
+	milestones []*github.Milestone
+	// pulls in order of adding, with milestone number
+	pulls []*github.PullRequest
+	files map[string]string
+	refs  []*github.Reference
+	// comments of pulls in order of creating
+	comments  []*pullComment
+	checkRuns []*github.CheckRun
+	// comparisons are keyed by "base...head"
+	comparisons map[string]comparison
+	commitPulls map[string][]*github.PullRequest
+}
+
+// NewServer starts a fake server, call Close when finished
+func NewServer() *Server {
+	s := Server{
+		user:  &github.User{Login: github.String("releaser-bot")},
+		repos: make(map[types.Repo]*repoFixture),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return &s
+}
+
+// APIURL returns the base url for API client
+func (s *Server) APIURL() string {
+	return s.URL + "/"
+}
+
+// SetUser sets the authenticated user
+func (s *Server) SetUser(login string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = &github.User{Login: github.String(login)}
+}
+
+// AddRepo registers a repo
+func (s *Server) AddRepo(repo types.Repo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repo(repo)
+}
+
+// AddMilestone adds a milestone and returns it, the number is assigned automatically
+func (s *Server) AddMilestone(repo types.Repo, title, state string) *github.Milestone {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repo(repo)
+	number := len(r.milestones) + 1
+	milestone := github.Milestone{
+		ID:     github.Int64(int64(number)),
+		Number: github.Int(number),
+		Title:  github.String(title),
+		State:  github.String(state),
+	}
+	r.milestones = append(r.milestones, &milestone)
+	return &milestone
+}
+
+// AddPull adds a pull request, milestone of the pull is used for listing issues
+func (s *Server) AddPull(repo types.Repo, pull *github.PullRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repo(repo)
+	if pull.State == nil {
+		pull.State = github.String("closed")
+	}
+	if pull.HTMLURL == nil {
+		pull.HTMLURL = github.String(fmt.Sprintf("https://github.com/%s/pull/%d", repo, pull.GetNumber()))
+	}
+	r.pulls = append(r.pulls, pull)
+}
+
+// AddFile adds a file at default branch
+func (s *Server) AddFile(repo types.Repo, path, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repo(repo).files[strings.Trim(path, "/")] = content
+}
+
+// AddRef adds a ref like "refs/tags/v4.0.6"
+func (s *Server) AddRef(repo types.Repo, ref, sha string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repo(repo)
+	r.refs = append(r.refs, &github.Reference{
+		Ref: github.String(ref),
+		Object: &github.GitObject{
+			Type: github.String("commit"),
+			SHA:  github.String(sha),
+		},
+	})
+}
+
+func (s *Server) repo(repo types.Repo) *repoFixture {
+	r, ok := s.repos[repo]
+	if !ok {
+		r = &repoFixture{files: make(map[string]string)}
+		s.repos[repo] = r
+	}
+	return r
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Requests = append(s.Requests, r.Method+" "+r.URL.Path)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 1 && parts[0] == "user" {
+		writeJSON(w, http.StatusOK, s.user)
+		return
+	}
+	if len(parts) == 1 && parts[0] == "graphql" && r.Method == http.MethodPost {
+		s.graphql(w, r)
+		return
+	}
+	if len(parts) < 3 || parts[0] != "repos" {
+		notFound(w)
+		return
+	}
+	repo := types.Repo{Owner: parts[1], Repo: parts[2]}
+	fixture, ok := s.repos[repo]
+	if !ok && !(r.Method == http.MethodPost && len(parts) == 4 && parts[3] == "forks") {
+		notFound(w)
+		return
+	}
+	rest := parts[3:]
+
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, &github.Repository{
+			Name:     github.String(repo.Repo),
+			FullName: github.String(repo.String()),
+			Owner:    &github.User{Login: github.String(repo.Owner)},
+		})
+	case len(rest) == 1 && rest[0] == "forks" && r.Method == http.MethodPost:
+		fork := types.Repo{Owner: s.user.GetLogin(), Repo: repo.Repo}
+		s.repo(fork)
+		s.Forks = append(s.Forks, fork)
+		writeJSON(w, http.StatusAccepted, &github.Repository{FullName: github.String(fork.String())})
+	case len(rest) == 1 && rest[0] == "milestones":
+		s.listMilestones(w, r, fixture)
+	case len(rest) == 1 && rest[0] == "issues":
+		s.listIssues(w, r, fixture)
+	case len(rest) == 2 && rest[0] == "pulls" && r.Method == http.MethodGet:
+		number, _ := strconv.Atoi(rest[1])
+		for _, pull := range fixture.pulls {
+			if pull.GetNumber() == number {
+				writeJSON(w, http.StatusOK, pull)
+				return
+			}
+		}
+		notFound(w)
+	case len(rest) == 1 && rest[0] == "pulls" && r.Method == http.MethodPost:
+		s.createPull(w, r, repo)
+	case len(rest) >= 1 && rest[0] == "contents":
+		s.getContents(w, strings.Join(rest[1:], "/"), fixture)
+	case len(rest) == 2 && rest[0] == "issues" && r.Method == http.MethodPatch:
+		number, _ := strconv.Atoi(rest[1])
+		s.editIssue(w, r, fixture, number)
+	case len(rest) == 3 && rest[0] == "issues" && rest[2] == "comments":
+		number, _ := strconv.Atoi(rest[1])
+		s.pullComments(w, r, fixture, number)
+	case len(rest) == 3 && rest[0] == "issues" && rest[1] == "comments":
+		id, _ := strconv.ParseInt(rest[2], 10, 64)
+		s.pullComment(w, r, fixture, id)
+	case len(rest) == 3 && rest[0] == "commits" && rest[2] == "check-runs" && r.Method == http.MethodGet:
+		s.listCheckRuns(w, r, fixture, rest[1])
+	case len(rest) == 1 && rest[0] == "check-runs" && r.Method == http.MethodPost:
+		s.createCheckRun(w, r, fixture)
+	case len(rest) == 2 && rest[0] == "check-runs" && r.Method == http.MethodPatch:
+		id, _ := strconv.ParseInt(rest[1], 10, 64)
+		s.updateCheckRun(w, r, fixture, id)
+	case len(rest) == 2 && rest[0] == "compare" && r.Method == http.MethodGet:
+		s.compare(w, fixture, rest[1])
+	case len(rest) == 3 && rest[0] == "commits" && rest[2] == "pulls" && r.Method == http.MethodGet:
+		s.listCommitPulls(w, fixture, rest[1])
+	case len(rest) >= 2 && rest[0] == "git" && rest[1] == "refs":
+		s.listRefs(w, r, fixture, strings.Join(rest[2:], "/"))
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) listMilestones(w http.ResponseWriter, r *http.Request, fixture *repoFixture) {
+	state := r.URL.Query().Get("state")
+	var milestones []*github.Milestone
+	for _, milestone := range fixture.milestones {
+		if state == "all" || milestone.GetState() == stateOrOpen(state) {
+			milestones = append(milestones, milestone)
+		}
+	}
+	writeJSON(w, http.StatusOK, paginate(r, len(milestones), func(i int) interface{} { return milestones[i] }))
+}
+
+func (s *Server) listIssues(w http.ResponseWriter, r *http.Request, fixture *repoFixture) {
+	var (
+		query     = r.URL.Query()
+		milestone = query.Get("milestone")
+		state     = query.Get("state")
+		issues    []*github.Issue
+	)
+	for _, pull := range fixture.pulls {
+		if milestone != "" && fmt.Sprintf("%d", pull.GetMilestone().GetNumber()) != milestone {
+			continue
+		}
+		if state != "all" && pull.GetState() != stateOrOpen(state) {
+			continue
+		}
+		issues = append(issues, pull2issue(pull))
+	}
+	writeJSON(w, http.StatusOK, paginate(r, len(issues), func(i int) interface{} { return issues[i] }))
+}
+
+func (s *Server) createPull(w http.ResponseWriter, r *http.Request, repo types.Repo) {
+	var newPull github.NewPullRequest
+	if err := json.NewDecoder(r.Body).Decode(&newPull); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	for _, created := range s.CreatedPulls {
+		if created.Repo == repo && created.Pull.GetHead() == newPull.GetHead() {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+				"message": "Validation Failed",
+				"errors": []map[string]string{
+					{"message": "A pull request already exists for " + newPull.GetHead() + "."},
+				},
+			})
+			return
+		}
+	}
+	s.CreatedPulls = append(s.CreatedPulls, CreatedPull{Repo: repo, Pull: newPull})
+	writeJSON(w, http.StatusCreated, &github.PullRequest{
+		Number: github.Int(len(s.CreatedPulls)),
+		Title:  newPull.Title,
+		Body:   newPull.Body,
+	})
+}
+
+func (s *Server) getContents(w http.ResponseWriter, p string, fixture *repoFixture) {
+	p = strings.Trim(p, "/")
+	if content, ok := fixture.files[p]; ok {
+		writeJSON(w, http.StatusOK, fileContent(p, content))
+		return
+	}
+
+	// list direct children of a directory
+	var (
+		dir      []*github.RepositoryContent
+		children = make(map[string]bool)
+		prefix   = p + "/"
+	)
+	if p == "" {
+		prefix = ""
+	}
+	for filePath := range fixture.files {
+		if !strings.HasPrefix(filePath, prefix) {
+			continue
+		}
+		name := strings.SplitN(strings.TrimPrefix(filePath, prefix), "/", 2)
+		if children[name[0]] {
+			continue
+		}
+		children[name[0]] = true
+		tp := "file"
+		if len(name) == 2 {
+			tp = "dir"
+		}
+		dir = append(dir, &github.RepositoryContent{
+			Name: github.String(name[0]),
+			Path: github.String(prefix + name[0]),
+			Type: github.String(tp),
+		})
+	}
+	if len(dir) == 0 {
+		notFound(w)
+		return
+	}
+	sort.Slice(dir, func(i, j int) bool { return dir[i].GetName() < dir[j].GetName() })
+	writeJSON(w, http.StatusOK, dir)
+}
+
+func fileContent(p, content string) *github.RepositoryContent {
+	name := p[strings.LastIndex(p, "/")+1:]
+	return &github.RepositoryContent{
+		Name:     github.String(name),
+		Path:     github.String(p),
+		Type:     github.String("file"),
+		Encoding: github.String("base64"),
+		Content:  github.String(base64.StdEncoding.EncodeToString([]byte(content))),
+		HTMLURL:  github.String("https://github.com/blob/master/" + p),
+	}
+}
+
+func pull2issue(pull *github.PullRequest) *github.Issue {
+	return &github.Issue{
+		ID:        pull.ID,
+		Number:    pull.Number,
+		State:     pull.State,
+		Title:     pull.Title,
+		Body:      pull.Body,
+		User:      pull.User,
+		Labels:    pull.Labels,
+		Milestone: pull.Milestone,
+		HTMLURL:   pull.HTMLURL,
+		PullRequestLinks: &github.PullRequestLinks{
+			HTMLURL: pull.HTMLURL,
+		},
+	}
+}
+
+// CountRequests counts recorded requests with the prefix
+func (s *Server) CountRequests(prefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, req := range s.Requests {
+		if strings.HasPrefix(req, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+// listRefs lists refs like GitHub, a ref matches path exactly is responded as object,
+// otherwise refs start with path are listed, 404 if there is no such ref
+func (s *Server) listRefs(w http.ResponseWriter, r *http.Request, fixture *repoFixture, path string) {
+	var refs []*github.Reference
+	for _, ref := range fixture.refs {
+		if path != "" && ref.GetRef() == "refs/"+path {
+			writeJSON(w, http.StatusOK, ref)
+			return
+		}
+		if strings.HasPrefix(ref.GetRef(), "refs/"+path) {
+			refs = append(refs, ref)
+		}
+	}
+	if path != "" && len(refs) == 0 {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, paginate(r, len(refs), func(i int) interface{} { return refs[i] }))
+}
+
+// paginate returns items of the requested page
+func paginate(r *http.Request, total int, item func(i int) interface{}) []interface{} {
+	var (
+		query      = r.URL.Query()
+		page, _    = strconv.Atoi(query.Get("page"))
+		perPage, _ = strconv.Atoi(query.Get("per_page"))
+		items      = []interface{}{}
+	)
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+	for i := (page - 1) * perPage; i < total && i < page*perPage; i++ {
+		items = append(items, item(i))
+	}
+	return items
+}
+
+func stateOrOpen(state string) string {
+	if state == "" {
+		return "open"
+	}
+	return state
+}
+
+func notFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// editIssue sets milestone of a pull by milestone number, null removes the milestone
+func (s *Server) editIssue(w http.ResponseWriter, r *http.Request, fixture *repoFixture, number int) {
+	var req struct {
+		Milestone *int `json:"milestone"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	for _, pull := range fixture.pulls {
+		if pull.GetNumber() != number {
+			continue
+		}
+		pull.Milestone = nil
+		if req.Milestone != nil {
+			for _, milestone := range fixture.milestones {
+				if milestone.GetNumber() == *req.Milestone {
+					pull.Milestone = milestone
+				}
+			}
+			if pull.Milestone == nil {
+				writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, pull2issue(pull))
+		return
+	}
+	notFound(w)
+}
@@ -0,0 +1,171 @@
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+const (
+	headerETag         = "ETag"
+	headerLastModified = "Last-Modified"
+	headerIfNoneMatch  = "If-None-Match"
+	headerIfModified   = "If-Modified-Since"
+	// headerCache tells whether the response is served from cache, for debugging
+	headerCache = "X-Releaser-Cache"
+)
+
+// Transport is a http.RoundTripper which stores GET responses on disk
+// and revalidates them by ETag, 304 responses don't count against GitHub rate limit
+type Transport struct {
+	// Base is the underlying transport, http.DefaultTransport is used if nil
+	Base http.RoundTripper
+	// Dir is where responses are stored
+	Dir string
+}
+
+// NewTransport creates Transport instance
+func NewTransport(base http.RoundTripper, dir string) *Transport {
+	return &Transport{
+		Base: base,
+		Dir:  dir,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.base().RoundTrip(req)
+	}
+
+	var (
+		file   = t.file(req)
+		cached = t.load(file, req)
+	)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get(headerETag); etag != "" {
+			req.Header.Set(headerIfNoneMatch, etag)
+		}
+		if modified := cached.Header.Get(headerLastModified); modified != "" {
+			req.Header.Set(headerIfModified, modified)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		// keep fresh headers like rate limit from 304 response
+		for k, v := range resp.Header {
+			cached.Header[k] = v
+		}
+		cached.Header.Set(headerCache, "hit")
+		return cached, nil
+	}
+	if resp.StatusCode == http.StatusOK && (resp.Header.Get(headerETag) != "" || resp.Header.Get(headerLastModified) != "") {
+		if err := t.store(file, resp); err != nil {
+			log.Warnf("store cache of %s failed, %v", req.URL, err)
+		}
+	}
+	return resp, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// file is keyed by url and the headers GitHub varies responses by
+func (t *Transport) file(req *http.Request) string {
+	h := sha256.New()
+	for _, s := range []string{req.URL.String(), req.Header.Get("Accept"), req.Header.Get("Authorization")} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	return path.Join(t.Dir, key[:2], key)
+}
+
+func (t *Transport) load(file string, req *http.Request) *http.Response {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(content)), req)
+	if err != nil {
+		log.Warnf("broken cache %s, %v", file, err)
+		return nil
+	}
+	return resp
+}
+
+// store reads the whole body and replaces it, the dumped response is written atomically
+func (t *Transport) store(file string, resp *http.Response) error {
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.MkdirAll(path.Dir(file), 0700); err != nil {
+		return errors.Trace(err)
+	}
+	tmp, err := ioutil.TempFile(path.Dir(file), ".tmp-")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(dump); err != nil {
+		tmp.Close()
+		return errors.Trace(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Rename(tmp.Name(), file))
+}
+
+// Clear removes all cached responses in dir, other files in dir are kept
+func Clear(dir string) (int, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || !isBucket(entry.Name()) {
+			continue
+		}
+		files, err := ioutil.ReadDir(path.Join(dir, entry.Name()))
+		if err != nil {
+			return removed, errors.Trace(err)
+		}
+		if err := os.RemoveAll(path.Join(dir, entry.Name())); err != nil {
+			return removed, errors.Trace(err)
+		}
+		removed += len(files)
+	}
+	return removed, nil
+}
+
+// isBucket checks if name is a two hex digits directory created by cache
+func isBucket(name string) bool {
+	_, err := hex.DecodeString(name)
+	return len(name) == 2 && err == nil
+}
@@ -0,0 +1,102 @@
+package httpcache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// etagServer serves body with ETag and counts full responses
+type etagServer struct {
+	mu     sync.Mutex
+	body   string
+	etag   string
+	full   int
+	notMod int
+}
+
+func (s *etagServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("X-RateLimit-Remaining", "4999")
+	if r.Header.Get(headerIfNoneMatch) == s.etag {
+		s.notMod++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.full++
+	w.Header().Set(headerETag, s.etag)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(s.body))
+}
+
+func get(t *testing.T, client *http.Client, url, token string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "token "+token)
+	resp, err := client.Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	return resp, string(body)
+}
+
+func TestTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "releaser-cache")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	s := etagServer{body: `{"number":1}`, etag: `"v1"`}
+	server := httptest.NewServer(http.HandlerFunc(s.serve))
+	defer server.Close()
+	client := &http.Client{Transport: NewTransport(nil, dir)}
+
+	resp, body := get(t, client, server.URL+"/pulls/1", "a")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, body, `{"number":1}`)
+	assert.Equal(t, resp.Header.Get(headerCache), "")
+
+	// revalidated
+	resp, body = get(t, client, server.URL+"/pulls/1", "a")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, body, `{"number":1}`)
+	assert.Equal(t, resp.Header.Get(headerCache), "hit")
+	assert.Equal(t, resp.Header.Get("Content-Type"), "application/json")
+	assert.Equal(t, s.full, 1)
+	assert.Equal(t, s.notMod, 1)
+
+	// another token doesn't share cache
+	get(t, client, server.URL+"/pulls/1", "b")
+	assert.Equal(t, s.full, 2)
+
+	// changed
+	s.body, s.etag = `{"number":2}`, `"v2"`
+	_, body = get(t, client, server.URL+"/pulls/1", "a")
+	assert.Equal(t, body, `{"number":2}`)
+	_, body = get(t, client, server.URL+"/pulls/1", "a")
+	assert.Equal(t, body, `{"number":2}`)
+	assert.Equal(t, s.full, 3)
+
+	// POST is not cached
+	_, err = client.Post(server.URL+"/graphql", "application/json", nil)
+	require.Nil(t, err)
+	assert.Equal(t, s.full, 4)
+
+	// other files are kept
+	require.Nil(t, ioutil.WriteFile(path.Join(dir, "keep"), nil, 0644))
+	removed, err := Clear(dir)
+	require.Nil(t, err)
+	assert.Equal(t, removed, 2)
+	_, err = os.Stat(path.Join(dir, "keep"))
+	assert.Nil(t, err)
+	get(t, client, server.URL+"/pulls/1", "a")
+	assert.Equal(t, s.full, 5)
+}
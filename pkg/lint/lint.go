@@ -0,0 +1,186 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/you06/releaser/config"
+)
+
+const (
+	// RuleMissingNote is the rule that pulls with required labels should have release notes
+	RuleMissingNote = "missing-note"
+	// RuleTooShort is the rule of min length of notes
+	RuleTooShort = "too-short"
+	// RuleTooLong is the rule of max length of notes
+	RuleTooLong = "too-long"
+	// RuleRepeatTitle is the rule that notes should not repeat pull titles
+	RuleRepeatTitle = "repeat-title"
+	// RuleTrailingPeriod is the rule that notes should end with a period
+	RuleTrailingPeriod = "trailing-period"
+	// RuleLowercase is the rule that notes should start with an uppercase letter
+	RuleLowercase = "lowercase"
+	// RuleBacktick is the rule that backticks in notes should be balanced
+	RuleBacktick = "backtick"
+)
+
+var (
+	// DefaultRequireNoteLabels are labels of pulls which need release notes if it's not configured
+	DefaultRequireNoteLabels = []string{"type/bug-fix", "type/new-feature"}
+	// DefaultMinLength is the min length of notes if it's not configured
+	DefaultMinLength = 10
+	// DefaultMaxLength is the max length of notes if it's not configured
+	DefaultMaxLength = 300
+
+	// titleScopePattern matches scope of pull titles, like "executor: "
+	titleScopePattern = regexp.MustCompile(`^[\w/*.,\- ]+:\s*`)
+	fencePattern      = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// Finding is a problem of release note found by a rule
+type Finding struct {
+	Rule    string
+	Message string
+	// Note is the note has the problem, it's empty if the problem is about pull
+	Note string
+}
+
+// String ...
+func (f Finding) String() string {
+	if f.Note == "" {
+		return fmt.Sprintf("[%s] %s", f.Rule, f.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", f.Rule, f.Message, firstLine(f.Note))
+}
+
+// Linter checks release notes of pulls by rules
+type Linter struct {
+	RequireNoteLabels []string
+	MinLength         int
+	MaxLength         int
+}
+
+// New creates Linter from config, empty fields use the default values
+func New(cfg config.Lint) *Linter {
+	l := Linter{
+		RequireNoteLabels: cfg.RequireNoteLabels,
+		MinLength:         cfg.MinLength,
+		MaxLength:         cfg.MaxLength,
+	}
+	if len(l.RequireNoteLabels) == 0 {
+		l.RequireNoteLabels = DefaultRequireNoteLabels
+	}
+	if l.MinLength == 0 {
+		l.MinLength = DefaultMinLength
+	}
+	if l.MaxLength == 0 {
+		l.MaxLength = DefaultMaxLength
+	}
+	return &l
+}
+
+// Lint checks notes extracted from pull
+func (l *Linter) Lint(pull *github.PullRequest, notes []string) []Finding {
+	var findings []Finding
+	if len(notes) == 0 {
+		for _, label := range pull.Labels {
+			for _, required := range l.RequireNoteLabels {
+				if strings.EqualFold(label.GetName(), required) {
+					findings = append(findings, Finding{
+						Rule:    RuleMissingNote,
+						Message: fmt.Sprintf("pull with label %s should have release note", label.GetName()),
+					})
+				}
+			}
+		}
+		return findings
+	}
+
+	for _, note := range notes {
+		findings = append(findings, l.lintNote(pull, note)...)
+	}
+	return findings
+}
+
+func (l *Linter) lintNote(pull *github.PullRequest, note string) []Finding {
+	var (
+		findings  []Finding
+		paragraph = firstParagraph(note)
+		length    = utf8.RuneCountInString(paragraph)
+		add       = func(rule, format string, args ...interface{}) {
+			findings = append(findings, Finding{Rule: rule, Message: fmt.Sprintf(format, args...), Note: note})
+		}
+	)
+	if length < l.MinLength {
+		add(RuleTooShort, "note is shorter than %d characters", l.MinLength)
+	}
+	if length > l.MaxLength {
+		add(RuleTooLong, "note is longer than %d characters", l.MaxLength)
+	}
+	if normalize(paragraph) == normalize(pull.GetTitle()) ||
+		normalize(paragraph) == normalize(titleScopePattern.ReplaceAllString(pull.GetTitle(), "")) {
+		add(RuleRepeatTitle, "note repeats the pull title")
+	}
+	if !strings.HasSuffix(paragraph, ".") && !strings.HasSuffix(paragraph, "。") {
+		add(RuleTrailingPeriod, "note should end with a period")
+	}
+	if first, _ := utf8.DecodeRuneInString(paragraph); unicode.IsLower(first) {
+		add(RuleLowercase, "note should start with an uppercase letter")
+	}
+	if !balancedBackticks(note) {
+		add(RuleBacktick, "backticks are not balanced")
+	}
+	return findings
+}
+
+// firstParagraph gets the first paragraph of note, the rest like fenced code is not checked by most rules
+func firstParagraph(note string) string {
+	var lines []string
+	for _, line := range strings.Split(note, "\n") {
+		if strings.TrimSpace(line) == "" || fencePattern.MatchString(line) {
+			break
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return strings.Join(lines, " ")
+}
+
+func firstLine(note string) string {
+	return strings.SplitN(note, "\n", 2)[0]
+}
+
+// normalize lowercases text and removes characters other than letters and digits
+func normalize(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, text)
+}
+
+// balancedBackticks checks inline code spans and fences are closed
+func balancedBackticks(note string) bool {
+	var (
+		fence  string
+		inline int
+	)
+	for _, line := range strings.Split(note, "\n") {
+		if match := fencePattern.FindStringSubmatch(line); len(match) == 2 {
+			if fence == "" {
+				fence = match[1]
+			} else if fence == match[1] {
+				fence = ""
+			}
+			continue
+		}
+		if fence == "" {
+			inline += strings.Count(line, "`")
+		}
+	}
+	return fence == "" && inline%2 == 0
+}
@@ -0,0 +1,45 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/you06/releaser/config"
+)
+
+func testPull(title string, labels ...string) *github.PullRequest {
+	pull := github.PullRequest{Title: github.String(title)}
+	for _, label := range labels {
+		pull.Labels = append(pull.Labels, &github.Label{Name: github.String(label)})
+	}
+	return &pull
+}
+
+func rules(findings []Finding) []string {
+	var res []string
+	for _, finding := range findings {
+		res = append(res, finding.Rule)
+	}
+	return res
+}
+
+func TestLint(t *testing.T) {
+	l := New(config.Lint{})
+	assert.Equal(t, rules(l.Lint(testPull("executor: fix panic", "type/bug-fix"), nil)), []string{RuleMissingNote})
+	assert.Equal(t, rules(l.Lint(testPull("executor: fix panic", "type/enhancement"), nil)), []string(nil))
+	assert.Equal(t, rules(l.Lint(testPull("executor: fix panic"), []string{"Fix a panic when `SELECT` is killed."})), []string(nil))
+	assert.Equal(t, rules(l.Lint(testPull("executor: fix panic"), []string{"fix panic"})),
+		[]string{RuleTooShort, RuleRepeatTitle, RuleTrailingPeriod, RuleLowercase})
+	assert.Equal(t, rules(l.Lint(testPull("executor: fix panic"), []string{"Fix a panic when `SELECT is killed."})),
+		[]string{RuleBacktick})
+	assert.Equal(t, rules(l.Lint(testPull("executor: fix panic"), []string{"Support the new syntax:\n\n```sql\nSELECT 1\n```"})),
+		[]string{RuleTrailingPeriod})
+
+	l = New(config.Lint{RequireNoteLabels: []string{"needs-note"}, MaxLength: 20})
+	assert.Equal(t, rules(l.Lint(testPull("executor: fix panic", "type/bug-fix"), nil)), []string(nil))
+	assert.Equal(t, rules(l.Lint(testPull("executor: fix panic", "needs-note"), []string{"Fix a panic when the query is killed."})),
+		[]string{RuleTooLong})
+	assert.Equal(t, l.Lint(testPull("title", "needs-note"), nil)[0].String(),
+		"[missing-note] pull with label needs-note should have release note")
+}
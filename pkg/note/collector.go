@@ -1,46 +1,41 @@
 package note
 
 import (
+	"context"
 	"path"
-	"regexp"
-	"strconv"
 	"strings"
 
 	"github.com/google/go-github/v30/github"
 	"github.com/juju/errors"
 	"github.com/you06/releaser/config"
+	"github.com/you06/releaser/pkg/forge"
 	"github.com/you06/releaser/pkg/parser"
 	"github.com/you06/releaser/pkg/types"
 	"github.com/you06/releaser/pkg/utils"
 )
 
 var langs = []string{"cn", "en", "jp"}
-var (
-	commentPattern  = regexp.MustCompile(`<!--[^>]*-->`)
-	repoPattern     = regexp.MustCompile(`^## (.*)$`)
-	releaseNoteLine = regexp.MustCompile(`^- ?(.*?)([a-zA-Z0-9-]+)\/([a-zA-Z0-9-]+)#([0-9]+).*$`)
-)
 
 // Collector for collect release notes
 type Collector struct {
-	github         *github.Client
+	forge          forge.Forge
 	Config         *config.Config
 	relaseNoteRepo types.Repo
 }
 
 // New creates Collector instance
-func New(g *github.Client, config *config.Config, relaseNoteRepo types.Repo) *Collector {
-	return &Collector{g, config, relaseNoteRepo}
+func New(f forge.Forge, config *config.Config, relaseNoteRepo types.Repo) *Collector {
+	return &Collector{f, config, relaseNoteRepo}
 }
 
 // ListReleaseNote lists release notes
-func (c *Collector) ListReleaseNote(product types.Product, version string) ([]parser.ReleaseNoteLang, error) {
+func (c *Collector) ListReleaseNote(ctx context.Context, product types.Product, version string) ([]parser.ReleaseNoteLang, error) {
 	var (
 		filePath = strings.ReplaceAll(c.Config.ReleaseNotePath, "{product}", product.Name)
 		notes    []parser.ReleaseNoteLang
 	)
 	trimVersion := strings.ToLower(strings.Trim(version, "v"))
-	contents, err := c.ListContents(filePath, version)
+	contents, err := c.ListContents(ctx, filePath, version)
 	if err != nil {
 		if strings.Contains(err.Error(), "404 Not Found") {
 			return notes, nil
@@ -55,93 +50,51 @@ func (c *Collector) ListReleaseNote(product types.Product, version string) ([]pa
 		if content.GetType() != "file" {
 			continue
 		}
-		lang, match := matchLang(name, trimVersion)
+		lang, match := matchLang(name, trimVersion, c.langs(), c.Config.PullLanguage)
 		if !match {
 			continue
 		}
-		releaseNotes, err := c.ParseContent(fullPath)
+		releaseNote, err := c.ParseContent(ctx, product, fullPath)
 		if err != nil {
 			return notes, errors.Trace(err)
 		}
-		notes = append(notes, parser.ReleaseNoteLang{
-			Lang:               lang,
-			Path:               fullPath,
-			Version:            version,
-			ReleaseNoteClasses: map[string][]parser.RepoReleaseNotes{"Others": releaseNotes},
-		})
+		releaseNote.Name = product.Name
+		releaseNote.Lang = lang
+		releaseNote.Path = fullPath
+		releaseNote.Version = version
+		releaseNote.Structure = product.Structure
+		notes = append(notes, *releaseNote)
 	}
 	return notes, nil
 }
 
 // ListContents list contents in a path
-func (c *Collector) ListContents(filePath, version string) ([]*github.RepositoryContent, error) {
+func (c *Collector) ListContents(ctx context.Context, filePath, version string) ([]*github.RepositoryContent, error) {
 	// FIXME: should use full name of the repo
-	ctx, _ := utils.NewTimeoutContext()
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
 	// TODO: what will happen if there are more than 100 files?
-	_, contents, _, err := c.github.Repositories.GetContents(ctx,
-		c.relaseNoteRepo.Owner, c.relaseNoteRepo.Repo, filePath, &github.RepositoryContentGetOptions{})
+	_, contents, err := c.forge.GetContents(ctx, c.relaseNoteRepo, filePath, "")
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 	return contents, nil
 }
 
-// ParseContent parses content and get all release notes
-func (c *Collector) ParseContent(fullPath string) ([]parser.RepoReleaseNotes, error) {
-	var (
-		repos      []parser.RepoReleaseNotes
-		reposNotes *parser.RepoReleaseNotes
-	)
-	content, err := c.GetFileContent(fullPath)
+// ParseContent parses content of release note file
+func (c *Collector) ParseContent(ctx context.Context, product types.Product, fullPath string) (*parser.ReleaseNoteLang, error) {
+	content, err := c.GetFileContent(ctx, fullPath)
 	if err != nil {
-		return repos, errors.Trace(err)
-	}
-
-	content = commentPattern.ReplaceAllString(content, "")
-	content = strings.ReplaceAll(content, "\r", "")
-
-	for _, line := range strings.Split(content, "\n") {
-		line = strings.Trim(line, " ")
-
-		repoMatch := repoPattern.FindStringSubmatch(line)
-		if len(repoMatch) == 2 {
-			// push old repo into repos
-			if reposNotes != nil && reposNotes.Repo.Repo != "" {
-				repos = append(repos, *reposNotes)
-			}
-			// compose new repo
-			reposNotes = &parser.RepoReleaseNotes{
-				Repo: types.Repo{Repo: repoMatch[1]},
-			}
-			continue
-		}
-
-		match := releaseNoteLine.FindStringSubmatch(line)
-		if len(match) != 5 {
-			continue
-		}
-		note, owner, repo, numberStr := match[1], match[2], match[3], match[4]
-		number, err := strconv.Atoi(numberStr)
-		if err != nil {
-			return repos, errors.Trace(err)
-		}
-		reposNotes.Notes = append(reposNotes.Notes, parser.ReleaseNote{
-			Repo: types.Repo{
-				Owner: owner,
-				Repo:  repo,
-			},
-			PullNumber: number,
-			Note:       note,
-		})
+		return nil, errors.Trace(err)
 	}
-	return repos, nil
+	return parser.Parse(content, repoNames(product)), nil
 }
 
 // GetFileContent gets content of file and decode it to string
-func (c *Collector) GetFileContent(p string) (string, error) {
-	ctx, _ := utils.NewTimeoutContext()
-	content, _, _, err := c.github.Repositories.GetContents(ctx,
-		c.relaseNoteRepo.Owner, c.relaseNoteRepo.Repo, p, &github.RepositoryContentGetOptions{})
+func (c *Collector) GetFileContent(ctx context.Context, p string) (string, error) {
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
+	content, _, err := c.forge.GetContents(ctx, c.relaseNoteRepo, p, "")
 	if err != nil {
 		return "", errors.Trace(err)
 	}
@@ -155,15 +108,44 @@ func (c *Collector) GetFileContent(p string) (string, error) {
 	return decoded, nil
 }
 
-func matchLang(name, version string) (string, bool) {
+// langs returns known languages and configured ones
+func (c *Collector) langs() []string {
+	res := append([]string{}, langs...)
+	for _, lang := range c.Config.ReleaseNoteLanguages() {
+		known := false
+		for _, l := range res {
+			known = known || l == lang
+		}
+		if !known {
+			res = append(res, lang)
+		}
+	}
+	return res
+}
+
+// matchLang gets language of release note file named <version>.md or <version>-<lang>.md,
+// file without language in name is in defaultLang
+func matchLang(name, version string, langs []string, defaultLang string) (string, bool) {
 	name = strings.ToLower(name)
-	if !strings.Contains(name, version) {
-		return "", false
+	if name == version+".md" {
+		return defaultLang, true
 	}
 	for _, lang := range langs {
-		if strings.Contains(name, lang) {
+		if name == version+"-"+lang+".md" {
 			return lang, true
 		}
 	}
-	return "en", true
+	return "", false
+}
+
+// repoNames maps display names of repos in release note to repos
+func repoNames(product types.Product) map[string]types.Repo {
+	names := make(map[string]types.Repo)
+	for _, repo := range product.Repos {
+		names[repo.Repo] = repo
+		if rename, ok := product.Renames[repo]; ok {
+			names[rename.Repo] = repo
+		}
+	}
+	return names
 }
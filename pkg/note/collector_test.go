@@ -0,0 +1,28 @@
+package note
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchLang(t *testing.T) {
+	langs := []string{"en", "cn"}
+	for _, c := range []struct {
+		name  string
+		lang  string
+		match bool
+	}{
+		{"4.0.6.md", "en", true},
+		{"4.0.6-cn.md", "cn", true},
+		{"4.0.6-CN.md", "cn", true},
+		{"4.0.6-jp.md", "", false},
+		{"4.0.60.md", "", false},
+		{"14.0.6.md", "", false},
+		{"4.0.6-rc.md", "", false},
+	} {
+		lang, match := matchLang(c.name, "4.0.6", langs, "en")
+		assert.Equal(t, lang, c.lang, c.name)
+		assert.Equal(t, match, c.match, c.name)
+	}
+}
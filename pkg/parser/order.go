@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"sort"
+
+	"github.com/juju/errors"
+)
+
+const (
+	// NOTE_ORDER_NUMBER sorts notes by pull request number
+	NOTE_ORDER_NUMBER = "number"
+	// NOTE_ORDER_MERGED_AT sorts notes by the time pull requests are merged
+	NOTE_ORDER_MERGED_AT = "merged-at"
+	// NOTE_ORDER_AUTHOR sorts notes by author of pull requests
+	NOTE_ORDER_AUTHOR = "author"
+)
+
+// DEFAULT_TYPE_ORDER is the order of type sections if it's not configured
+var DEFAULT_TYPE_ORDER = []string{"Compatibility Changes", "New Features", "Improvements", "Bug Fixes"}
+
+// ClassOrder sorts type classes, classes in order come first, the rest are sorted by name
+// and Others is the last if it's not in order. DEFAULT_TYPE_ORDER is used if order is empty.
+func ClassOrder(classes map[string][]RepoReleaseNotes, order []string) []string {
+	if len(order) == 0 {
+		order = DEFAULT_TYPE_ORDER
+	}
+	var (
+		names   []string
+		ordered = make(map[string]bool)
+	)
+	for _, name := range order {
+		ordered[name] = true
+		if _, ok := classes[name]; ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range sortedClasses(classes) {
+		if !ordered[name] && name != OTHER_TYPE {
+			names = append(names, name)
+		}
+	}
+	if _, ok := classes[OTHER_TYPE]; ok && !ordered[OTHER_TYPE] {
+		names = append(names, OTHER_TYPE)
+	}
+	return names
+}
+
+// CheckNoteOrder checks if order is a valid note order, empty means NOTE_ORDER_NUMBER
+func CheckNoteOrder(order string) error {
+	switch order {
+	case "", NOTE_ORDER_NUMBER, NOTE_ORDER_MERGED_AT, NOTE_ORDER_AUTHOR:
+		return nil
+	default:
+		return errors.Errorf("invalid note order %s, should be one of %s, %s and %s",
+			order, NOTE_ORDER_NUMBER, NOTE_ORDER_MERGED_AT, NOTE_ORDER_AUTHOR)
+	}
+}
+
+// SortNotes sorts notes of every repo in order, pull request number breaks ties,
+// and hand-written notes keep their order after notes of pull requests
+func (r *ReleaseNoteLang) SortNotes(order string) {
+	less := func(a, b ReleaseNote) bool {
+		switch order {
+		case NOTE_ORDER_MERGED_AT:
+			if !a.MergedAt.Equal(b.MergedAt) {
+				return a.MergedAt.Before(b.MergedAt)
+			}
+		case NOTE_ORDER_AUTHOR:
+			if a.Author != b.Author {
+				return a.Author < b.Author
+			}
+		}
+		return a.PullNumber < b.PullNumber
+	}
+	for _, repos := range r.ReleaseNoteClasses {
+		for _, repoNotes := range repos {
+			notes := repoNotes.Notes
+			sort.SliceStable(notes, func(i, j int) bool {
+				if (notes[i].PullNumber == 0) != (notes[j].PullNumber == 0) {
+					return notes[j].PullNumber == 0
+				}
+				if notes[i].PullNumber == 0 {
+					return false
+				}
+				return less(notes[i], notes[j])
+			})
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package parser
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/you06/releaser/pkg/types"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+const frontMatterDelimiter = "---"
+
+var (
+	titlePattern    = regexp.MustCompile(`(?m)^title:\s*(.+) (\S+) Release Notes\s*$`)
+	datePattern     = regexp.MustCompile(`(?m)^Release date:\s*(.+?)\s*$`)
+	pullLinkPattern = regexp.MustCompile(`/([\w.-]+)/([\w.-]+)/pull/(\d+)/?$`)
+)
+
+// Parse parses release note document by its Markdown AST, the document is usually written by ReleaseNoteLang.String.
+// It rebuilds name, version and release date from header, type classes from level 2 headings,
+// product structure and repos from nested lists, and notes with their pull request links.
+// names maps display names of repos to repos, it's used by repos which have no linked notes.
+// Header which is not generated by default template and sections which are not lists of notes are kept as is.
+func Parse(content string, names map[string]types.Repo) *ReleaseNoteLang {
+	var (
+		r = ReleaseNoteLang{
+			ReleaseNoteClasses: make(map[string][]RepoReleaseNotes),
+		}
+		source      = []byte(strings.ReplaceAll(content, "\r", ""))
+		bodyStart   = frontMatterEnd(source)
+		body        = source[bodyStart:]
+		doc         = goldmark.DefaultParser().Parse(text.NewReader(body))
+		headerEnd   = len(body)
+		titles      []string
+		headings    []ast.Node
+		sectionEnds []int
+	)
+
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		if heading, ok := n.(*ast.Heading); ok && heading.Level == 2 {
+			start := lineStart(body, blockStart(heading))
+			if len(headings) == 0 {
+				headerEnd = start
+			} else {
+				sectionEnds = append(sectionEnds, start)
+			}
+			headings = append(headings, heading)
+			titles = append(titles, strings.TrimSpace(string(heading.Text(body))))
+		}
+	}
+	sectionEnds = append(sectionEnds, len(body))
+
+	r.parseHeader(strings.Trim(string(source[:bodyStart])+string(body[:headerEnd]), "\n"))
+
+	for i, heading := range headings {
+		var (
+			section = Section{Title: titles[i]}
+			start   = lineEnd(body, blockStart(heading))
+			end     = sectionEnds[i]
+			blocks  []ast.Node
+		)
+		for n := heading.NextSibling(); n != nil && (i+1 == len(headings) || n != headings[i+1]); n = n.NextSibling() {
+			blocks = append(blocks, n)
+		}
+
+		// hand-written text followed by lists of notes
+		listStart := len(blocks)
+		for j, block := range blocks {
+			if block.Kind() == ast.KindList {
+				listStart = j
+				break
+			}
+		}
+		contentEnd := end
+		if listStart < len(blocks) {
+			contentEnd = lineStart(body, blockStart(blocks[listStart]))
+		}
+
+		b := classBuilder{source: body, names: names}
+		ok := true
+		for _, block := range blocks[listStart:] {
+			if block.Kind() != ast.KindList {
+				ok = false
+				break
+			}
+			r.Structure = mergeStructure(r.Structure, b.items(block, nil, ""))
+			if b.err {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			section.Content = strings.Trim(string(body[start:contentEnd]), "\n")
+			if len(b.repos) > 0 {
+				r.ReleaseNoteClasses[section.Title] = b.repos
+			}
+		} else {
+			section.Content = strings.Trim(string(body[start:end]), "\n")
+		}
+		r.Sections = append(r.Sections, section)
+	}
+	return &r
+}
+
+// parseHeader gets name, version and release date from header,
+// the header is kept only if it's different from the generated one
+func (r *ReleaseNoteLang) parseHeader(header string) {
+	if match := titlePattern.FindStringSubmatch(header); len(match) == 3 {
+		r.Name, r.Version = match[1], match[2]
+	}
+	if match := datePattern.FindStringSubmatch(header); len(match) == 2 {
+		if date, err := time.Parse(DATE_FORMAT, match[1]); err == nil {
+			r.Date = date
+		}
+	}
+	if r.generatedHeader() != header {
+		r.Header = header
+	}
+}
+
+// classBuilder collects notes and product structure from lists in a class section
+type classBuilder struct {
+	source []byte
+	names  map[string]types.Repo
+	repos  []RepoReleaseNotes
+	// err means there is something can not be converted to notes
+	err bool
+}
+
+// items converts list into product items, notes in list are added to repo,
+// repo is nil if the list is not under any repo
+func (b *classBuilder) items(list ast.Node, repo *types.Repo, label string) []types.ProductItem {
+	var items []types.ProductItem
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		var (
+			textBlock ast.Node
+			subLists  []ast.Node
+		)
+		for c := item.FirstChild(); c != nil; c = c.NextSibling() {
+			switch c.Kind() {
+			case ast.KindList:
+				subLists = append(subLists, c)
+			case ast.KindTextBlock, ast.KindParagraph:
+				if textBlock == nil {
+					textBlock = c
+				}
+			}
+		}
+		if textBlock == nil {
+			b.err = true
+			return items
+		}
+		itemText := b.text(textBlock)
+
+		if note, ok := b.note(textBlock, itemText); ok {
+			note.Note += b.rest(textBlock)
+			noteRepo := note.Repo
+			if repo != nil {
+				noteRepo = *repo
+			}
+			noteLabel := label
+			if noteLabel == "" {
+				noteLabel = noteRepo.Repo
+			}
+			b.addNote(noteRepo, noteLabel, note)
+			continue
+		}
+
+		if len(subLists) == 0 {
+			// hand-written note belongs to the repo of its list
+			if repo == nil {
+				b.err = true
+				return items
+			}
+			b.addNote(*repo, label, ReleaseNote{Repo: *repo, Note: itemText + b.rest(textBlock)})
+			continue
+		}
+
+		if itemRepo, ok := b.repoOf(itemText, subLists); ok {
+			items = append(items, types.ProductItem{Repo: itemRepo})
+			for _, subList := range subLists {
+				b.items(subList, &itemRepo, itemText)
+			}
+			continue
+		}
+		productItem := types.ProductItem{Title: itemText}
+		for _, subList := range subLists {
+			productItem.Children = append(productItem.Children, b.items(subList, nil, "")...)
+		}
+		items = append(items, productItem)
+	}
+	return items
+}
+
+// repoOf checks if a list item with sub lists is a repo, which has notes as direct children.
+// The repo is found by names, or by linked pulls whose repo name is same as label, or by the first linked pull.
+func (b *classBuilder) repoOf(label string, subLists []ast.Node) (types.Repo, bool) {
+	var (
+		isRepo bool
+		linked []types.Repo
+	)
+	for _, subList := range subLists {
+		for item := subList.FirstChild(); item != nil; item = item.NextSibling() {
+			hasSubList := false
+			for c := item.FirstChild(); c != nil; c = c.NextSibling() {
+				if c.Kind() == ast.KindList {
+					hasSubList = true
+				}
+				if c.Kind() == ast.KindTextBlock || c.Kind() == ast.KindParagraph {
+					if note, ok := b.note(c, b.text(c)); ok {
+						linked = append(linked, note.Repo)
+					}
+				}
+			}
+			if !hasSubList {
+				isRepo = true
+			}
+		}
+	}
+	if !isRepo {
+		return types.Repo{}, false
+	}
+	if repo, ok := b.names[label]; ok {
+		return repo, true
+	}
+	for _, repo := range linked {
+		if strings.EqualFold(repo.Repo, label) {
+			return repo, true
+		}
+	}
+	if len(linked) > 0 {
+		return linked[0], true
+	}
+	return types.Repo{Repo: label}, true
+}
+
+// note gets release note from a text with pull request link, the last link is used if there are many
+func (b *classBuilder) note(textBlock ast.Node, itemText string) (ReleaseNote, bool) {
+	var link *ast.Link
+	_ = ast.Walk(textBlock, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if l, ok := n.(*ast.Link); ok && entering && pullLinkPattern.Match(l.Destination) {
+			link = l
+		}
+		return ast.WalkContinue, nil
+	})
+	if link == nil {
+		return ReleaseNote{}, false
+	}
+
+	var (
+		match     = pullLinkPattern.FindSubmatch(link.Destination)
+		number, _ = strconv.Atoi(string(match[3]))
+		raw       = "[" + string(link.Text(b.source)) + "](" + string(link.Destination) + ")"
+		loc       = strings.LastIndex(itemText, raw)
+	)
+	if loc < 0 {
+		return ReleaseNote{}, false
+	}
+	note := ReleaseNote{
+		Repo:       types.Repo{Owner: string(match[1]), Repo: string(match[2])},
+		PullNumber: number,
+		Note:       strings.TrimSpace(itemText[:loc]),
+	}
+	if strings.TrimSpace(itemText[loc+len(raw):]) == REMOVED_FLAG {
+		note.Removed = true
+	}
+	return note, true
+}
+
+// text gets markdown source of a text block, lines are joined by space
+func (b *classBuilder) text(block ast.Node) string {
+	var lines []string
+	for i := 0; i < block.Lines().Len(); i++ {
+		segment := block.Lines().At(i)
+		lines = append(lines, strings.TrimSpace(string(segment.Value(b.source))))
+	}
+	return strings.Join(lines, " ")
+}
+
+// rest gets lines indented inside list item after its first text block, like fenced code of multi-line notes,
+// lines are dedented to the text block
+func (b *classBuilder) rest(textBlock ast.Node) string {
+	var (
+		lines  = textBlock.Lines()
+		start  = lines.At(0).Start
+		indent = start - lineStart(b.source, start)
+		rest   []string
+	)
+	for offset := lineEnd(b.source, lines.At(lines.Len()-1).Start); offset < len(b.source); {
+		end := lineEnd(b.source, offset)
+		line := strings.TrimRight(string(b.source[offset:end]), "\n")
+		offset = end
+		if strings.TrimSpace(line) == "" {
+			rest = append(rest, "")
+			continue
+		}
+		if len(line)-len(strings.TrimLeft(line, " ")) < indent {
+			break
+		}
+		rest = append(rest, line[indent:])
+	}
+	for len(rest) > 0 && rest[len(rest)-1] == "" {
+		rest = rest[:len(rest)-1]
+	}
+	if len(rest) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(rest, "\n")
+}
+
+func (b *classBuilder) addNote(repo types.Repo, label string, note ReleaseNote) {
+	for i := range b.repos {
+		if b.repos[i].Repo == repo {
+			b.repos[i].Notes = append(b.repos[i].Notes, note)
+			return
+		}
+	}
+	b.repos = append(b.repos, RepoReleaseNotes{
+		Repo:   repo,
+		Rename: types.Repo{Owner: repo.Owner, Repo: label},
+		Notes:  []ReleaseNote{note},
+	})
+}
+
+// mergeStructure appends items which are not in structure yet, items of title are merged recursively
+func mergeStructure(structure, items []types.ProductItem) []types.ProductItem {
+	for _, item := range items {
+		found := false
+		for i := range structure {
+			if item.Title != "" && structure[i].Title == item.Title {
+				structure[i].Children = mergeStructure(structure[i].Children, item.Children)
+				found = true
+				break
+			}
+			if item.Title == "" && structure[i].Title == "" && structure[i].Repo == item.Repo {
+				found = true
+				break
+			}
+		}
+		if !found {
+			structure = append(structure, item)
+		}
+	}
+	return structure
+}
+
+// frontMatterEnd returns the offset after front matter, 0 if there is no front matter
+func frontMatterEnd(source []byte) int {
+	delimiter := []byte(frontMatterDelimiter + "\n")
+	if !bytes.HasPrefix(source, delimiter) {
+		return 0
+	}
+	end := bytes.Index(source[len(delimiter):], []byte("\n"+frontMatterDelimiter))
+	if end < 0 {
+		return 0
+	}
+	return lineEnd(source, len(delimiter)+end+1)
+}
+
+// blockStart returns the offset of the first line of a block
+func blockStart(n ast.Node) int {
+	for ; n != nil; n = n.FirstChild() {
+		if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+			return n.Lines().At(0).Start
+		}
+	}
+	return 0
+}
+
+func lineStart(source []byte, offset int) int {
+	return bytes.LastIndexByte(source[:offset], '\n') + 1
+}
+
+func lineEnd(source []byte, offset int) int {
+	end := bytes.IndexByte(source[offset:], '\n')
+	if end < 0 {
+		return len(source)
+	}
+	return offset + end + 1
+}
+
+// Merge adds release notes of generated into r which is parsed from existing document.
+// Notes of pull requests already in r keep their text and class, only new pull requests are added,
+// other content of r such as header and hand-written sections are kept as is.
+// New pull requests are added in the order of generated, and new classes follow the type order of generated.
+// Repos new to r are placed by the structure of generated.
+func (r *ReleaseNoteLang) Merge(generated *ReleaseNoteLang) {
+	if r.ReleaseNoteClasses == nil {
+		r.ReleaseNoteClasses = make(map[string][]RepoReleaseNotes)
+	}
+	r.TypeOrder = generated.TypeOrder
+	r.Structure = mergeStructure(r.Structure, generated.Structure)
+
+	type pullKey struct {
+		repo   types.Repo
+		number int
+	}
+	existing := make(map[pullKey]bool)
+	for _, repos := range r.ReleaseNoteClasses {
+		for _, repoNotes := range repos {
+			for _, note := range repoNotes.Notes {
+				existing[pullKey{note.Repo, note.PullNumber}] = true
+			}
+		}
+	}
+
+	for _, class := range sortedClasses(generated.ReleaseNoteClasses) {
+		for _, repoNotes := range generated.ReleaseNoteClasses[class] {
+			for _, note := range repoNotes.Notes {
+				if existing[pullKey{note.Repo, note.PullNumber}] {
+					continue
+				}
+				r.addNote(class, repoNotes.Repo, repoNotes.Rename, note)
+			}
+		}
+	}
+}
+
+func (r *ReleaseNoteLang) addNote(class string, repo, rename types.Repo, note ReleaseNote) {
+	repos := r.ReleaseNoteClasses[class]
+	for i := range repos {
+		if repos[i].Repo == repo {
+			repos[i].Notes = append(repos[i].Notes, note)
+			return
+		}
+	}
+	r.ReleaseNoteClasses[class] = append(repos, RepoReleaseNotes{
+		Repo:   repo,
+		Rename: rename,
+		Notes:  []ReleaseNote{note},
+	})
+}
+
+// FlagRemoved flags notes whose pull requests are not in milestone any more,
+// and clears the flag of pull requests added back. It returns all flagged notes.
+func (r *ReleaseNoteLang) FlagRemoved(inMilestone func(repo types.Repo, number int) bool) []ReleaseNote {
+	var removed []ReleaseNote
+	for _, class := range sortedClasses(r.ReleaseNoteClasses) {
+		for _, repoNotes := range r.ReleaseNoteClasses[class] {
+			for i := range repoNotes.Notes {
+				note := &repoNotes.Notes[i]
+				if note.PullNumber == 0 {
+					continue
+				}
+				note.Removed = !inMilestone(note.Repo, note.PullNumber)
+				if note.Removed {
+					removed = append(removed, *note)
+				}
+			}
+		}
+	}
+	return removed
+}
+
+func sortedClasses(classes map[string][]RepoReleaseNotes) []string {
+	var names []string
+	for name := range classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
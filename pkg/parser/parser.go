@@ -2,34 +2,18 @@ package parser
 
 import (
 	"fmt"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
-	"github.com/juju/errors"
 	"github.com/you06/releaser/pkg/types"
 )
 
 const (
-	FOUR_SPACE = "    "
-	OTHER_TYPE = "Others"
-	HEADER     = `---
-title: {name} {version} Release Notes
-category: Releases
-aliases: ['/docs/dev/releases/{version}/']
----
-
-# {name} {version} Release Notes
-
-Release date: {date}
-
-TiDB version: {version}`
+	FOUR_SPACE  = "    "
+	OTHER_TYPE  = "Others"
 	DATE_FORMAT = "January 02, 2006"
-)
-
-var (
-	releaseNoteLine = regexp.MustCompile(`^- ?(.*?)([a-zA-Z0-9-]+)\/([a-zA-Z0-9-]+)#([0-9]+).*$`)
+	// REMOVED_FLAG marks notes whose pull requests are removed from milestone
+	REMOVED_FLAG = "<!-- releaser: removed from milestone -->"
 )
 
 // ReleaseNoteLang collects all release notes of a language
@@ -40,6 +24,22 @@ type ReleaseNoteLang struct {
 	ReleaseNoteClasses map[string][]RepoReleaseNotes
 	Structure          []types.ProductItem
 	Version            string
+	// Date is the release date, now if it's zero
+	Date time.Time
+	// Header is kept from existing document, generated by template if empty
+	Header string
+	// Sections is the order of level 2 sections in existing document
+	Sections []Section
+	// TypeOrder is the order of type sections which are not in Sections, see ClassOrder
+	TypeOrder []string
+}
+
+// Section is a level 2 section of release note document, which may be a class
+// of release notes, hand-written text or both
+type Section struct {
+	Title string
+	// Content is hand-written text kept as is, it's written before release notes of the class
+	Content string
 }
 
 // RepoReleaseNotes defines release notes in a repo
@@ -54,29 +54,25 @@ type ReleaseNote struct {
 	Repo       types.Repo
 	PullNumber int
 	Note       string
+	// Removed means the pull request is no longer in milestone
+	Removed bool
+	// Author and MergedAt are used to sort notes, they are not written into document
+	Author   string
+	MergedAt time.Time
+	// Translations are notes in other languages written in pull request, they are used instead of placeholders
+	Translations map[string]string
 }
 
-// ParseContent parse content
+// ParseContent parses content and gets all release notes
 func ParseContent(content string) ([]ReleaseNote, error) {
-	var notes []ReleaseNote
-	for _, line := range strings.Split(strings.ReplaceAll(content, "\r", ""), "\n") {
-		match := releaseNoteLine.FindStringSubmatch(strings.Trim(line, " "))
-		if len(match) != 5 {
-			continue
-		}
-		note, owner, repo, numberStr := match[1], match[2], match[3], match[4]
-		number, err := strconv.Atoi(numberStr)
-		if err != nil {
-			return notes, errors.Trace(err)
+	var (
+		r     = Parse(content, nil)
+		notes []ReleaseNote
+	)
+	for _, class := range sortedClasses(r.ReleaseNoteClasses) {
+		for _, repoNotes := range r.ReleaseNoteClasses[class] {
+			notes = append(notes, repoNotes.Notes...)
 		}
-		notes = append(notes, ReleaseNote{
-			Repo: types.Repo{
-				Owner: owner,
-				Repo:  repo,
-			},
-			PullNumber: number,
-			Note:       note,
-		})
 	}
 	return notes, nil
 }
@@ -90,9 +86,46 @@ func (r *ReleaseNoteLang) RemoveAllNote() {
 	}
 }
 
-// String ...
+// HasPull checks if the pull request of a repo is noted in any class
+func (r *ReleaseNoteLang) HasPull(repo types.Repo, number int) bool {
+	for _, repos := range r.ReleaseNoteClasses {
+		for _, repoNotes := range repos {
+			for _, note := range repoNotes.Notes {
+				if note.PullNumber == number && note.Repo.Repo == repo.Repo &&
+					(note.Repo.Owner == "" || note.Repo.Owner == repo.Owner) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// String writes pull request link at the end of the first paragraph,
+// the rest of multi-line note, like fenced code, follows it
 func (r ReleaseNote) String() string {
-	return fmt.Sprintf("%s [#%d](https://github.com/%s/pull/%d)", Ucfirst(r.Note), r.PullNumber, r.Repo.String(), r.PullNumber)
+	// hand-written note without pull request
+	if r.PullNumber == 0 {
+		return r.Note
+	}
+	paragraph, rest := splitFirstParagraph(r.Note)
+	s := fmt.Sprintf("%s [#%d](https://github.com/%s/pull/%d)", Ucfirst(paragraph), r.PullNumber, r.Repo.String(), r.PullNumber)
+	if r.Removed {
+		s += " " + REMOVED_FLAG
+	}
+	return s + rest
+}
+
+// splitFirstParagraph splits note before the first blank line or fenced code
+func splitFirstParagraph(note string) (string, string) {
+	lines := strings.Split(note, "\n")
+	for i, line := range lines[1:] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			return strings.Join(lines[:i+1], "\n"), "\n" + strings.Join(lines[i+1:], "\n")
+		}
+	}
+	return note, ""
 }
 
 // String ...
@@ -110,119 +143,48 @@ func (r RepoReleaseNotes) String() string {
 	return b.String()
 }
 
-func (r ReleaseNoteLang) formatHeader() string {
-	header := strings.ReplaceAll(HEADER, "{name}", r.Name)
-	header = strings.ReplaceAll(header, "{version}", r.Version)
-	header = strings.ReplaceAll(header, "{date}", time.Now().Format(DATE_FORMAT))
-	return header
+// generatedHeader renders the header of default template
+func (r ReleaseNoteLang) generatedHeader() string {
+	header, _ := ReleaseNoteLang{Name: r.Name, Version: r.Version, Date: r.Date}.Render(nil)
+	return strings.TrimRight(header, "\n")
 }
 
-// String ...
+// String renders release note by default template
 func (r ReleaseNoteLang) String() string {
-	var b strings.Builder
-
-	b.WriteString(r.formatHeader())
-	b.WriteString("\n\n")
+	s, err := r.Render(nil)
+	if err != nil {
+		return err.Error()
+	}
+	return s
+}
 
-	var haveReleaseNote func(item types.ProductItem, repos []RepoReleaseNotes) bool
-	haveReleaseNote = func(item types.ProductItem, repos []RepoReleaseNotes) bool {
+func collectStructureRepos(structure []types.ProductItem, repos map[types.Repo]bool) {
+	for _, item := range structure {
 		if item.Title == "" {
-			var repo RepoReleaseNotes
-			for _, r := range repos {
-				if r.Repo == item.Repo {
-					repo = r
-					break
-				}
-			}
-			return len(repo.Notes) != 0
-		}
-
-		for _, projectItem := range item.Children {
-			if haveReleaseNote(projectItem, repos) {
-				return true
-			}
+			repos[item.Repo] = true
 		}
-		return false
+		collectStructureRepos(item.Children, repos)
 	}
+}
 
-	var writeProjectItems func(b *strings.Builder, depth int, structure []types.ProductItem, repos []RepoReleaseNotes)
-	writeProjectItems = func(b *strings.Builder, depth int, structure []types.ProductItem, repos []RepoReleaseNotes) {
-		for _, projectItem := range structure {
-			// skip repos don't have release notes
-			if !haveReleaseNote(projectItem, repos) {
-				continue
-			}
-
-			// write header
-			if depth == 0 {
-				b.WriteString("+ ")
-			} else if depth == 1 {
-				b.WriteString(FOUR_SPACE)
-				b.WriteString("- ")
-			} else {
-				for i := 0; i < depth; i++ {
-					b.WriteString(FOUR_SPACE)
-				}
-				b.WriteString("* ")
-			}
-
-			// recursive go deeper
-			if projectItem.Title != "" {
-				fmt.Fprintf(b, "%s\n\n", projectItem.Title)
-				writeProjectItems(b, depth+1, projectItem.Children, repos)
-				continue
-			}
-
-			// find repo
-			var repo RepoReleaseNotes
-			for _, r := range repos {
-				if r.Repo == projectItem.Repo {
-					repo = r
-					break
-				}
-			}
-
-			// list repos
-			if repo.Rename.Repo != "" {
-				b.WriteString(repo.Rename.Repo)
-			} else {
-				b.WriteString(repo.Repo.Repo)
-			}
-			b.WriteString("\n\n")
-
-			for _, note := range repo.Notes {
-				indent := depth + 1
-				if indent == 1 {
-					b.WriteString(FOUR_SPACE)
-					b.WriteString("- ")
-				} else {
-					for i := 0; i < indent; i++ {
-						b.WriteString(FOUR_SPACE)
-					}
-					b.WriteString("* ")
-				}
-				b.WriteString(note.String())
-				b.WriteString("\n")
+func haveReleaseNote(item types.ProductItem, repos []RepoReleaseNotes) bool {
+	if item.Title == "" {
+		var repo RepoReleaseNotes
+		for _, r := range repos {
+			if r.Repo == item.Repo {
+				repo = r
+				break
 			}
-			b.WriteString("\n")
 		}
+		return len(repo.Notes) != 0
 	}
 
-	for tp, repos := range r.ReleaseNoteClasses {
-		if tp == OTHER_TYPE {
-			continue
+	for _, projectItem := range item.Children {
+		if haveReleaseNote(projectItem, repos) {
+			return true
 		}
-		fmt.Fprintf(&b, "## %s\n\n", tp)
-		writeProjectItems(&b, 0, r.Structure, repos)
 	}
-	if repos, ok := r.ReleaseNoteClasses[OTHER_TYPE]; ok {
-		fmt.Fprintf(&b, "## %s\n\n", OTHER_TYPE)
-		writeProjectItems(&b, 0, r.Structure, repos)
-	}
-
-	// remove last "\n" character, a little hack
-	res := b.String()
-	return res[:len(res)-1]
+	return false
 }
 
 func Ucfirst(str string) string {
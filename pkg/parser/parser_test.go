@@ -2,8 +2,11 @@ package parser
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you06/releaser/pkg/types"
 )
 
 func TestBigLetter(t *testing.T) {
@@ -17,3 +20,308 @@ func TestBigLetter(t *testing.T) {
 	assert.Equal(t, Ucfirst("虵"), "虵")
 	assert.Equal(t, Ucfirst("hA"), "HA")
 }
+
+const existingDoc = `---
+title: TiDB 4.0.6 Release Notes
+---
+
+# TiDB 4.0.6 Release Notes
+
+Release date: September 15, 2020
+
+## Upgrade
+
+Read the upgrade guide first.
+
+## Bug Fixes
+
++ TiDB
+
+    - Fix the panic when executor is closed (edited) [#100](https://github.com/pingcap/tidb/pull/100)
+    - Hand-written note without pull request
+
++ Tools
+
+    - Backup & Restore (BR)
+
+        * Fix restore failure [#10](https://github.com/pingcap/br/pull/10)
+
+## Others
+
++ PD
+
+    - Dropped feature [#201](https://github.com/pingcap/pd/pull/201)
+`
+
+func TestParseMerge(t *testing.T) {
+	var (
+		tidb = types.Repo{Owner: "pingcap", Repo: "tidb"}
+		pd   = types.Repo{Owner: "pingcap", Repo: "pd"}
+		br   = types.Repo{Owner: "pingcap", Repo: "br"}
+	)
+	structure := []types.ProductItem{
+		{Repo: tidb},
+		{Repo: pd},
+		{Title: "Tools", Children: []types.ProductItem{{Repo: br}}},
+	}
+
+	doc := Parse(existingDoc, map[string]types.Repo{"TiDB": tidb, "PD": pd})
+	doc.Structure = structure
+	assert.Equal(t, doc.Header, "---\ntitle: TiDB 4.0.6 Release Notes\n---\n\n# TiDB 4.0.6 Release Notes\n\nRelease date: September 15, 2020")
+	assert.Equal(t, doc.Sections, []Section{
+		{Title: "Upgrade", Content: "Read the upgrade guide first."},
+		{Title: "Bug Fixes"},
+		{Title: "Others"},
+	})
+	assert.Equal(t, doc.ReleaseNoteClasses["Bug Fixes"][0].Notes, []ReleaseNote{
+		{Repo: tidb, PullNumber: 100, Note: "Fix the panic when executor is closed (edited)"},
+		{Repo: tidb, Note: "Hand-written note without pull request"},
+	})
+	assert.Equal(t, doc.ReleaseNoteClasses["Bug Fixes"][1].Rename.Repo, "Backup & Restore (BR)")
+	assert.True(t, doc.HasPull(br, 10))
+	assert.Equal(t, doc.String(), existingDoc, "unchanged document round-trips")
+
+	doc.Merge(&ReleaseNoteLang{
+		ReleaseNoteClasses: map[string][]RepoReleaseNotes{
+			"Bug Fixes": {{Repo: tidb, Rename: tidb, Notes: []ReleaseNote{
+				{Repo: tidb, PullNumber: 100, Note: "fix the panic"},
+				{Repo: tidb, PullNumber: 102, Note: "fix another panic"},
+			}}},
+			"New Features": {{Repo: pd, Rename: types.Repo{Repo: "PD"}, Notes: []ReleaseNote{
+				{Repo: pd, PullNumber: 202, Note: "support new API"},
+			}}},
+		},
+	})
+	removed := doc.FlagRemoved(func(repo types.Repo, number int) bool { return number != 201 })
+	assert.Equal(t, removed, []ReleaseNote{{Repo: pd, PullNumber: 201, Note: "Dropped feature", Removed: true}})
+
+	content := doc.String()
+	assert.Contains(t, content, "## Upgrade\n\nRead the upgrade guide first.\n\n## Bug Fixes")
+	assert.Contains(t, content, "(edited) [#100]")
+	assert.Contains(t, content, "    - Hand-written note without pull request\n    - Fix another panic [#102]")
+	assert.Contains(t, content, "## New Features\n\n+ PD\n\n    - Support new API [#202]")
+	assert.Contains(t, content, "Dropped feature [#201](https://github.com/pingcap/pd/pull/201) "+REMOVED_FLAG)
+
+	// flag is parsed back
+	assert.Equal(t, Parse(content, nil).HasPull(pd, 201), true)
+	assert.Equal(t, Parse(content, nil).ReleaseNoteClasses["Others"][0].Notes[0].Removed, true)
+}
+
+func TestMergeStructure(t *testing.T) {
+	var (
+		tidb  = types.Repo{Owner: "pingcap", Repo: "tidb"}
+		pd    = types.Repo{Owner: "pingcap", Repo: "pd"}
+		br    = types.Repo{Owner: "pingcap", Repo: "br"}
+		ticdc = types.Repo{Owner: "pingcap", Repo: "ticdc"}
+	)
+	doc := Parse(existingDoc, map[string]types.Repo{"TiDB": tidb, "PD": pd, "Backup & Restore (BR)": br})
+	doc.Merge(&ReleaseNoteLang{
+		Structure: []types.ProductItem{
+			{Repo: tidb},
+			{Repo: pd},
+			{Title: "Tools", Children: []types.ProductItem{{Repo: br}, {Repo: ticdc}}},
+		},
+		ReleaseNoteClasses: map[string][]RepoReleaseNotes{
+			"Bug Fixes": {{Repo: ticdc, Rename: types.Repo{Owner: "pingcap", Repo: "TiCDC"}, Notes: []ReleaseNote{
+				{Repo: ticdc, PullNumber: 20, Note: "fix a panic"},
+			}}},
+		},
+	})
+
+	// repo new to the file is rendered in its group
+	assert.Contains(t, doc.String(), "+ Tools\n\n    - Backup & Restore (BR)\n\n        * Fix restore failure [#10](https://github.com/pingcap/br/pull/10)\n\n"+
+		"    - TiCDC\n\n        * Fix a panic [#20](https://github.com/pingcap/ticdc/pull/20)\n")
+	assert.NotContains(t, doc.String(), "+ TiCDC")
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	var (
+		tidb  = types.Repo{Owner: "pingcap", Repo: "tidb"}
+		pd    = types.Repo{Owner: "pingcap", Repo: "pd"}
+		br    = types.Repo{Owner: "pingcap", Repo: "br"}
+		ticdc = types.Repo{Owner: "pingcap", Repo: "ticdc"}
+	)
+	releaseNote := ReleaseNoteLang{
+		Name:    "TiDB",
+		Version: "v4.0.6",
+		Date:    time.Date(2020, time.September, 15, 0, 0, 0, 0, time.UTC),
+		Structure: []types.ProductItem{
+			{Repo: tidb},
+			{Repo: pd},
+			{Title: "Tools", Children: []types.ProductItem{{Repo: br}, {Repo: ticdc}}},
+		},
+		Sections: []Section{{Title: "New Features"}, {Title: "Bug Fixes"}, {Title: OTHER_TYPE}},
+		ReleaseNoteClasses: map[string][]RepoReleaseNotes{
+			"New Features": {
+				{Repo: tidb, Rename: types.Repo{Owner: "pingcap", Repo: "TiDB"}, Notes: []ReleaseNote{
+					{Repo: tidb, PullNumber: 1, Note: "Support `SELECT ... FOR UPDATE` with [docs](https://docs.pingcap.com)"},
+					{Repo: tidb, PullNumber: 2, Note: "Add a new variable"},
+				}},
+				{Repo: pd, Rename: types.Repo{Owner: "pingcap", Repo: "PD"}, Notes: []ReleaseNote{
+					{Repo: pd, PullNumber: 3, Note: "Support placement rules"},
+				}},
+				{Repo: br, Rename: types.Repo{Owner: "pingcap", Repo: "Backup & Restore (BR)"}, Notes: []ReleaseNote{
+					{Repo: br, PullNumber: 4, Note: "Support S3"},
+				}},
+				{Repo: ticdc, Rename: types.Repo{Owner: "pingcap", Repo: "TiCDC"}, Notes: []ReleaseNote{
+					{Repo: ticdc, PullNumber: 5, Note: "Support Kafka sink"},
+				}},
+			},
+			"Bug Fixes": {
+				{Repo: tidb, Rename: types.Repo{Owner: "pingcap", Repo: "TiDB"}, Notes: []ReleaseNote{
+					{Repo: types.Repo{Owner: "pingcap", Repo: "parser"}, PullNumber: 6, Note: "Fix a parser bug"},
+					{Repo: tidb, Note: "Hand-written note"},
+				}},
+				{Repo: ticdc, Rename: types.Repo{Owner: "pingcap", Repo: "TiCDC"}, Notes: []ReleaseNote{
+					{Repo: ticdc, PullNumber: 7, Note: "Fix a panic", Removed: true},
+				}},
+			},
+			OTHER_TYPE: {
+				{Repo: pd, Rename: types.Repo{Owner: "pingcap", Repo: "PD"}, Notes: []ReleaseNote{
+					{Repo: pd, PullNumber: 8, Note: "Update dependencies"},
+				}},
+			},
+		},
+	}
+
+	content := releaseNote.String()
+	// notes of TiDB are all linked to other repo
+	parsed := Parse(content, map[string]types.Repo{"TiDB": tidb})
+	assert.Equal(t, *parsed, releaseNote)
+	assert.Equal(t, parsed.String(), content)
+
+	notes, err := ParseContent(content)
+	assert.Nil(t, err)
+	assert.Equal(t, len(notes), 9)
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tikv := types.Repo{Owner: "tikv", Repo: "tikv"}
+	releaseNote := ReleaseNoteLang{
+		Name:    "TiKV",
+		Version: "v4.0.6",
+		Date:    time.Date(2020, 9, 15, 0, 0, 0, 0, time.UTC),
+		ReleaseNoteClasses: map[string][]RepoReleaseNotes{
+			"Bug Fixes": {{Repo: tikv, Rename: types.Repo{Owner: "TiKV", Repo: "TiKV"}, Notes: []ReleaseNote{
+				{Repo: tikv, PullNumber: 1, Note: "fix a panic"},
+			}}},
+		},
+		Structure: []types.ProductItem{{Repo: tikv}},
+	}
+
+	tmpl, err := NewTemplate("tikv", `# {{ .Name }} {{ .Version }} ({{ .Date.Format "2006-01-02" }})
+{{ range .Sections }}
+### {{ .Title }}
+{{ range .Items }}{{ range .Notes }}
+* {{ $.Name }}: {{ ucfirst .Note }} (#{{ .PullNumber }}){{ end }}{{ end }}
+{{ end }}`)
+	require.Nil(t, err)
+	rendered, err := releaseNote.Render(tmpl)
+	require.Nil(t, err)
+	assert.Equal(t, rendered, "# TiKV v4.0.6 (2020-09-15)\n\n### Bug Fixes\n\n* TiKV: Fix a panic (#1)\n")
+
+	_, err = NewTemplate("broken", "{{ .Name ")
+	assert.NotNil(t, err)
+	tmpl, err = NewTemplate("unknown", "{{ .Unknown }}")
+	require.Nil(t, err)
+	_, err = releaseNote.Render(tmpl)
+	assert.NotNil(t, err)
+}
+
+func TestOrder(t *testing.T) {
+	classes := map[string][]RepoReleaseNotes{
+		OTHER_TYPE:              nil,
+		"Bug Fixes":             nil,
+		"Compatibility Changes": nil,
+		"New Features":          nil,
+		"Deprecations":          nil,
+	}
+	assert.Equal(t, ClassOrder(classes, nil),
+		[]string{"Compatibility Changes", "New Features", "Bug Fixes", "Deprecations", OTHER_TYPE})
+	assert.Equal(t, ClassOrder(classes, []string{"Bug Fixes", OTHER_TYPE, "Improvements"}),
+		[]string{"Bug Fixes", OTHER_TYPE, "Compatibility Changes", "Deprecations", "New Features"})
+
+	tidb := types.Repo{Owner: "pingcap", Repo: "tidb"}
+	day := func(d int) time.Time { return time.Date(2020, 9, d, 0, 0, 0, 0, time.UTC) }
+	releaseNote := ReleaseNoteLang{
+		ReleaseNoteClasses: map[string][]RepoReleaseNotes{
+			"Bug Fixes": {{Repo: tidb, Notes: []ReleaseNote{
+				{Repo: tidb, Note: "hand-written"},
+				{Repo: tidb, PullNumber: 3, Author: "alice", MergedAt: day(1)},
+				{Repo: tidb, PullNumber: 1, Author: "bob", MergedAt: day(3)},
+				{Repo: tidb, PullNumber: 2, Author: "alice", MergedAt: day(2)},
+			}}},
+		},
+	}
+	numbers := func() []int {
+		var res []int
+		for _, note := range releaseNote.ReleaseNoteClasses["Bug Fixes"][0].Notes {
+			res = append(res, note.PullNumber)
+		}
+		return res
+	}
+	releaseNote.SortNotes(NOTE_ORDER_NUMBER)
+	assert.Equal(t, numbers(), []int{1, 2, 3, 0})
+	releaseNote.SortNotes(NOTE_ORDER_MERGED_AT)
+	assert.Equal(t, numbers(), []int{3, 2, 1, 0})
+	releaseNote.SortNotes(NOTE_ORDER_AUTHOR)
+	assert.Equal(t, numbers(), []int{2, 3, 1, 0})
+
+	assert.Nil(t, CheckNoteOrder(""))
+	assert.NotNil(t, CheckNoteOrder("title"))
+}
+
+func TestMultiLineNote(t *testing.T) {
+	tidb := types.Repo{Owner: "pingcap", Repo: "tidb"}
+	releaseNote := ReleaseNoteLang{
+		Name:    "TiDB",
+		Version: "v4.0.6",
+		Date:    time.Date(2020, 9, 15, 0, 0, 0, 0, time.UTC),
+		ReleaseNoteClasses: map[string][]RepoReleaseNotes{
+			"Bug Fixes": {{Repo: tidb, Rename: types.Repo{Owner: "pingcap", Repo: "TiDB"}, Notes: []ReleaseNote{
+				{Repo: tidb, PullNumber: 1, Note: "Fix a panic:\n\n```sql\nSELECT 1;\n\n- not a bullet\n```"},
+				{Repo: tidb, PullNumber: 1, Note: "Support `SHOW CONFIG`"},
+			}}},
+		},
+		Structure: []types.ProductItem{{Repo: tidb}},
+		Sections:  []Section{{Title: "Bug Fixes"}},
+	}
+	content := releaseNote.String()
+	assert.Contains(t, content, "    - Fix a panic: [#1](https://github.com/pingcap/tidb/pull/1)\n\n"+
+		"      ```sql\n      SELECT 1;\n\n      - not a bullet\n      ```\n    - Support `SHOW CONFIG` [#1]")
+	assert.Equal(t, *Parse(content, map[string]types.Repo{"TiDB": tidb}), releaseNote)
+}
+
+func TestPlaceholder(t *testing.T) {
+	tidb := types.Repo{Owner: "pingcap", Repo: "tidb"}
+	for _, c := range []struct {
+		name     string
+		note     ReleaseNote
+		expected string
+	}{
+		{"translated", ReleaseNote{Repo: tidb, PullNumber: 1, Note: "fix a panic", Translations: map[string]string{"cn": "修复崩溃"}}, "修复崩溃"},
+		{"untranslated", ReleaseNote{Repo: tidb, PullNumber: 2, Note: "fix a bug"}, TRANSLATE_FLAG + "Fix a bug"},
+		{"flagged", ReleaseNote{Repo: tidb, PullNumber: 3, Note: TRANSLATE_FLAG + "修复"}, TRANSLATE_FLAG + "修复"},
+		{"hand-written", ReleaseNote{Repo: tidb, Note: "upgrade first"}, "upgrade first"},
+	} {
+		releaseNote := ReleaseNoteLang{
+			Lang:               "en",
+			Path:               "/tidb/4.0.6.md",
+			ReleaseNoteClasses: map[string][]RepoReleaseNotes{"Bug Fixes": {{Repo: tidb, Notes: []ReleaseNote{c.note}}}},
+		}
+		placeholder := releaseNote.Placeholder("cn", "/tidb/4.0.6-cn.md")
+		assert.Equal(t, placeholder.Lang, "cn", c.name)
+		assert.Equal(t, placeholder.Path, "/tidb/4.0.6-cn.md", c.name)
+		assert.Equal(t, placeholder.ReleaseNoteClasses["Bug Fixes"][0].Notes[0].Note, c.expected, c.name)
+		assert.Equal(t, releaseNote.ReleaseNoteClasses["Bug Fixes"][0].Notes[0], c.note, "source is not changed")
+	}
+
+	releaseNote := ReleaseNoteLang{ReleaseNoteClasses: map[string][]RepoReleaseNotes{"Bug Fixes": {{Repo: tidb, Notes: []ReleaseNote{
+		{Repo: tidb, PullNumber: 1, Note: "fix"},
+		{Repo: tidb, PullNumber: 2, Note: TRANSLATE_FLAG + "fix"},
+		{Repo: tidb, Note: "hand-written"},
+	}}}}}
+	untranslated, total := releaseNote.Untranslated()
+	assert.Equal(t, untranslated, 1)
+	assert.Equal(t, total, 2)
+}
@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/you06/releaser/pkg/types"
+)
+
+// DEFAULT_TEMPLATE renders release note document for PingCAP docs
+const DEFAULT_TEMPLATE = `
+{{- define "item" -}}
+{{ bullet .Depth }}{{ .Name }}
+
+{{ if .Title -}}
+{{ range .Children }}{{ template "item" . }}{{ end -}}
+{{ else -}}
+{{ range .Notes }}{{ bullet (add $.Depth 1) }}{{ indent (add $.Depth 1) .String }}
+{{ end }}
+{{ end -}}
+{{ end -}}
+
+{{- if .Header }}{{ .Header }}{{ else }}---
+title: {{ .Name }} {{ .Version }} Release Notes
+category: Releases
+aliases: ['/docs/dev/releases/{{ .Version }}/']
+---
+
+# {{ .Name }} {{ .Version }} Release Notes
+
+Release date: {{ date .Date }}
+
+TiDB version: {{ .Version }}{{ end }}
+
+{{ range .Sections }}## {{ .Title }}
+
+{{ with .Content }}{{ . }}
+
+{{ end }}{{ range .Items }}{{ template "item" . }}{{ end }}{{ end }}`
+
+var defaultTemplate = template.Must(NewTemplate("default", DEFAULT_TEMPLATE))
+
+// Document is the data of release note template
+type Document struct {
+	Name    string
+	Lang    string
+	Version string
+	// Date is the release date, now if it's not set in ReleaseNoteLang
+	Date time.Time
+	// Header is kept from existing document, template should generate header if it's empty
+	Header   string
+	Sections []DocumentSection
+}
+
+// DocumentSection is a level 2 section, which contains hand-written text and notes of a class
+type DocumentSection struct {
+	Title   string
+	Content string
+	Items   []DocumentItem
+}
+
+// DocumentItem is a title with children or a repo with notes, only items with notes are listed
+type DocumentItem struct {
+	// Depth starts from 0 at the top level of section
+	Depth    int
+	Title    string
+	Repo     types.Repo
+	Rename   types.Repo
+	Notes    []ReleaseNote
+	Children []DocumentItem
+}
+
+// Name is the title of item, or the repo name which may be renamed
+func (i DocumentItem) Name() string {
+	if i.Title != "" {
+		return i.Title
+	}
+	if i.Rename.Repo != "" {
+		return i.Rename.Repo
+	}
+	return i.Repo.Repo
+}
+
+// NewTemplate parses release note template with helper functions:
+// bullet gives indent and bullet of list item in depth, indent keeps the rest lines of text inside list item in depth,
+// add sums numbers, date formats date in DATE_FORMAT and ucfirst uppercases the first letter
+func NewTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(template.FuncMap{
+		"bullet":  bullet,
+		"indent":  indent,
+		"add":     func(a, b int) int { return a + b },
+		"date":    func(t time.Time) string { return t.Format(DATE_FORMAT) },
+		"ucfirst": Ucfirst,
+	}).Parse(text)
+	return t, errors.Trace(err)
+}
+
+// Render renders release note by template, default template is used if tmpl is nil.
+// The rendered document ends with a single newline.
+func (r ReleaseNoteLang) Render(tmpl *template.Template) (string, error) {
+	if tmpl == nil {
+		tmpl = defaultTemplate
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, r.Document()); err != nil {
+		return "", errors.Trace(err)
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n", nil
+}
+
+// Document converts release note to template data,
+// sections of existing document keep their order, new sections are sorted by ClassOrder
+func (r ReleaseNoteLang) Document() Document {
+	d := Document{
+		Name:    r.Name,
+		Lang:    r.Lang,
+		Version: r.Version,
+		Date:    r.Date,
+		Header:  r.Header,
+	}
+	if d.Date.IsZero() {
+		d.Date = time.Now()
+	}
+
+	written := make(map[string]bool)
+	for _, section := range r.Sections {
+		written[section.Title] = true
+		d.Sections = append(d.Sections, r.documentSection(section.Title, section.Content))
+	}
+	for _, tp := range ClassOrder(r.ReleaseNoteClasses, r.TypeOrder) {
+		if !written[tp] {
+			d.Sections = append(d.Sections, r.documentSection(tp, ""))
+		}
+	}
+	return d
+}
+
+func (r ReleaseNoteLang) documentSection(title, content string) DocumentSection {
+	repos := r.ReleaseNoteClasses[title]
+	section := DocumentSection{
+		Title:   title,
+		Content: content,
+		Items:   documentItems(0, r.Structure, repos),
+	}
+
+	// repos not in structure, like hand-written ones, are listed at top level
+	inStructure := make(map[types.Repo]bool)
+	collectStructureRepos(r.Structure, inStructure)
+	for _, repo := range repos {
+		if !inStructure[repo.Repo] && len(repo.Notes) != 0 {
+			section.Items = append(section.Items, repoItem(0, repo))
+		}
+	}
+	return section
+}
+
+func documentItems(depth int, structure []types.ProductItem, repos []RepoReleaseNotes) []DocumentItem {
+	var items []DocumentItem
+	for _, projectItem := range structure {
+		// skip repos don't have release notes
+		if !haveReleaseNote(projectItem, repos) {
+			continue
+		}
+
+		if projectItem.Title != "" {
+			items = append(items, DocumentItem{
+				Depth:    depth,
+				Title:    projectItem.Title,
+				Children: documentItems(depth+1, projectItem.Children, repos),
+			})
+			continue
+		}
+
+		for _, repo := range repos {
+			if repo.Repo == projectItem.Repo {
+				items = append(items, repoItem(depth, repo))
+				break
+			}
+		}
+	}
+	return items
+}
+
+func repoItem(depth int, repo RepoReleaseNotes) DocumentItem {
+	return DocumentItem{
+		Depth:  depth,
+		Repo:   repo.Repo,
+		Rename: repo.Rename,
+		Notes:  repo.Notes,
+	}
+}
+
+// bullet returns indent and bullet of list item in depth
+func bullet(depth int) string {
+	switch depth {
+	case 0:
+		return "+ "
+	case 1:
+		return FOUR_SPACE + "- "
+	default:
+		return strings.Repeat(FOUR_SPACE, depth) + "* "
+	}
+}
+
+// indent indents lines of text except the first one to be inside list item in depth, blank lines are kept empty
+func indent(depth int, text string) string {
+	var (
+		lines  = strings.Split(text, "\n")
+		spaces = strings.Repeat(" ", len(bullet(depth)))
+	)
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			lines[i] = spaces + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,55 @@
+package parser
+
+import "strings"
+
+// TRANSLATE_FLAG marks notes copied from source language which should be translated
+const TRANSLATE_FLAG = "TODO-translate: "
+
+// NeedTranslate checks if the note is copied from source language and not translated yet
+func (r ReleaseNote) NeedTranslate() bool {
+	return strings.HasPrefix(r.Note, TRANSLATE_FLAG)
+}
+
+// Placeholder copies release note into another language with the same structure,
+// notes of pull requests are replaced by their translations, or marked by TRANSLATE_FLAG if there is no translation
+func (r ReleaseNoteLang) Placeholder(lang, path string) *ReleaseNoteLang {
+	placeholder := r
+	placeholder.Lang = lang
+	placeholder.Path = path
+	placeholder.ReleaseNoteClasses = make(map[string][]RepoReleaseNotes, len(r.ReleaseNoteClasses))
+	for class, repos := range r.ReleaseNoteClasses {
+		copied := make([]RepoReleaseNotes, len(repos))
+		for i, repoNotes := range repos {
+			copied[i] = repoNotes
+			copied[i].Notes = make([]ReleaseNote, len(repoNotes.Notes))
+			for j, note := range repoNotes.Notes {
+				if translation, ok := note.Translations[lang]; ok {
+					note.Note = translation
+				} else if note.PullNumber != 0 && !note.NeedTranslate() {
+					note.Note = TRANSLATE_FLAG + Ucfirst(note.Note)
+				}
+				copied[i].Notes[j] = note
+			}
+		}
+		placeholder.ReleaseNoteClasses[class] = copied
+	}
+	return &placeholder
+}
+
+// Untranslated counts notes of pull requests and those need translation
+func (r ReleaseNoteLang) Untranslated() (untranslated, total int) {
+	for _, repos := range r.ReleaseNoteClasses {
+		for _, repoNotes := range repos {
+			for _, note := range repoNotes.Notes {
+				if note.PullNumber == 0 {
+					continue
+				}
+				total++
+				if note.NeedTranslate() {
+					untranslated++
+				}
+			}
+		}
+	}
+	return untranslated, total
+}
@@ -0,0 +1,165 @@
+package pull
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
+)
+
+var (
+	// DefaultCherryPickLabels are globs of labels added by the cherry-pick bot if they are not configured
+	DefaultCherryPickLabels = []string{"type/cherry-pick-for-*", "cherry-pick"}
+
+	// cherryPickBodyPattern matches bodies like "cherry-pick #12345 to release-4.0" or "automated cherry-pick of #12345"
+	cherryPickBodyPattern = regexp.MustCompile(`(?i)cherry[- ]?pick(?:ed)?\s+(?:of\s+)?#(\d+)`)
+)
+
+// cherryPickOf gets the number of the original pull which pull is cherry-picked from,
+// by body pattern first, then the "(#12345)" suffix of title
+func cherryPickOf(pull *github.PullRequest) (int, bool) {
+	for _, match := range [][]string{
+		cherryPickBodyPattern.FindStringSubmatch(pull.GetBody()),
+		squashPattern.FindStringSubmatch(strings.TrimSpace(pull.GetTitle())),
+	} {
+		if len(match) != 2 {
+			continue
+		}
+		if number, err := strconv.Atoi(match[1]); err == nil && number != pull.GetNumber() {
+			return number, true
+		}
+	}
+	return 0, false
+}
+
+// isCherryPickLabel checks if label is added by the cherry-pick bot
+func (c *Collector) isCherryPickLabel(label string) bool {
+	globs := c.Config.CherryPickLabels
+	if len(globs) == 0 {
+		globs = DefaultCherryPickLabels
+	}
+	for _, glob := range globs {
+		if matched, _ := path.Match(glob, label); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveCherryPicks links cherry-pick pulls to their originals.
+// A cherry-pick without release note inherits the body of its original, and the labels if it has only cherry-pick labels.
+// Originals are removed if their cherry-picks are in pulls too, so every change is listed once.
+// The number cited by a pull is taken as its original only if it's a pull of repo on another branch which pull links back to,
+// a pull whose original is not found is kept as it is.
+func (c *Collector) ResolveCherryPicks(ctx context.Context, repo types.Repo, pulls []*github.PullRequest) ([]*github.PullRequest, error) {
+	var (
+		originals = make([]*github.PullRequest, len(pulls))
+		collected = make(map[int]*github.PullRequest)
+		picked    = make(map[int]bool)
+	)
+	for _, pull := range pulls {
+		collected[pull.GetNumber()] = pull
+	}
+	err := utils.Parallel(ctx, c.Config.Concurrency, len(pulls), func(ctx context.Context, i int) error {
+		number, ok := cherryPickOf(pulls[i])
+		if !ok {
+			return nil
+		}
+		if original, ok := collected[number]; ok {
+			originals[i] = original
+			return nil
+		}
+		if !c.needInherit(repo, pulls[i]) {
+			return nil
+		}
+		original, err := c.getPull(ctx, repo, number)
+		if err != nil {
+			// the number may be an issue or a pull of another repo
+			if strings.Contains(err.Error(), "404 Not Found") {
+				log.Warnf("original #%d of %s#%d not found, %v", number, repo, pulls[i].GetNumber(), err)
+				return nil
+			}
+			return errors.Trace(err)
+		}
+		originals[i] = original
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for i, pull := range pulls {
+		if originals[i] == nil {
+			continue
+		}
+		if !linksBack(pull, originals[i]) {
+			originals[i] = nil
+			continue
+		}
+		if collected[originals[i].GetNumber()] != nil {
+			picked[originals[i].GetNumber()] = true
+		}
+	}
+
+	var res []*github.PullRequest
+	for i, pull := range pulls {
+		if picked[pull.GetNumber()] {
+			continue
+		}
+		if originals[i] != nil && c.needInherit(repo, pull) {
+			pull = c.inherit(repo, pull, originals[i])
+		}
+		res = append(res, pull)
+	}
+	return res, nil
+}
+
+// linksBack checks if pick is a cherry-pick of original, which is merged into another branch,
+// and is linked by the cherry-pick body or the title of original
+func linksBack(pick, original *github.PullRequest) bool {
+	if original.GetBase().GetRef() == pick.GetBase().GetRef() {
+		return false
+	}
+	if match := cherryPickBodyPattern.FindStringSubmatch(pick.GetBody()); len(match) == 2 && match[1] == strconv.Itoa(original.GetNumber()) {
+		return true
+	}
+	title := strings.TrimSpace(original.GetTitle())
+	return title != "" && strings.Contains(strings.ToLower(pick.GetTitle()), strings.ToLower(title))
+}
+
+// needInherit checks if a cherry-pick misses release note or labels
+func (c *Collector) needInherit(repo types.Repo, pull *github.PullRequest) bool {
+	return !c.hasNote(repo, pull) || !c.hasOwnLabels(pull)
+}
+
+func (c *Collector) hasNote(repo types.Repo, pull *github.PullRequest) bool {
+	return c.HasNote == nil || c.HasNote(repo, pull)
+}
+
+// hasOwnLabels checks if pull has labels other than cherry-pick labels
+func (c *Collector) hasOwnLabels(pull *github.PullRequest) bool {
+	for _, label := range pull.Labels {
+		if !c.isCherryPickLabel(label.GetName()) {
+			return true
+		}
+	}
+	return false
+}
+
+// inherit copies pull with the release note and labels of original where pull misses them
+func (c *Collector) inherit(repo types.Repo, pull, original *github.PullRequest) *github.PullRequest {
+	inherited := *pull
+	if !c.hasNote(repo, pull) {
+		inherited.Body = original.Body
+	}
+	if !c.hasOwnLabels(pull) {
+		inherited.Labels = append(append([]*github.Label{}, pull.Labels...), original.Labels...)
+	}
+	return &inherited
+}
@@ -0,0 +1,150 @@
+package pull
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you06/releaser/config"
+	"github.com/you06/releaser/pkg/githubtest"
+	"github.com/you06/releaser/pkg/types"
+)
+
+func TestCherryPickOf(t *testing.T) {
+	for _, c := range []struct {
+		title, body string
+		number      int
+		ok          bool
+	}{
+		{"executor: fix panic (#12345)", "", 12345, true},
+		{"executor: fix panic", "cherry-pick #12345 to release-4.0", 12345, true},
+		{"executor: fix panic", "This is an automated cherry-pick of #12345", 12345, true},
+		{"executor: fix panic (#1)", "cherry pick #12345 to release-4.0", 12345, true},
+		{"executor: fix panic", "close #12345", 0, false},
+		{"executor: fix panic (#100)", "", 0, false},
+	} {
+		number, ok := cherryPickOf(&github.PullRequest{
+			Number: github.Int(100),
+			Title:  github.String(c.title),
+			Body:   github.String(c.body),
+		})
+		assert.Equal(t, ok, c.ok, c.title)
+		assert.Equal(t, number, c.number, c.title)
+	}
+}
+
+func TestIsCherryPickLabel(t *testing.T) {
+	c := New(nil, &config.Config{})
+	assert.True(t, c.isCherryPickLabel("type/cherry-pick-for-release-4.0"))
+	assert.False(t, c.isCherryPickLabel("type/bug-fix"))
+	c.Config.CherryPickLabels = []string{"needs-cherry-pick-*"}
+	assert.True(t, c.isCherryPickLabel("needs-cherry-pick-4.0"))
+	assert.False(t, c.isCherryPickLabel("type/cherry-pick-for-release-4.0"))
+}
+
+func TestResolveCherryPicks(t *testing.T) {
+	onMaster := func(pull *github.PullRequest) *github.PullRequest {
+		pull.Base = &github.PullRequestBranch{Ref: github.String("master")}
+		return pull
+	}
+	original := onMaster(testPull(100, nil, "executor: fix panic", "### Release note\n- fix a panic", "type/bug-fix"))
+	for _, c := range []struct {
+		name string
+		// pulls are collected pulls, originals are only on server
+		pulls     []*github.PullRequest
+		originals []*github.PullRequest
+		numbers   []int
+		body      string
+		labels    []string
+		fetched   int
+	}{
+		{
+			name:      "inherit note and labels",
+			pulls:     []*github.PullRequest{testPull(200, nil, "executor: fix panic", "cherry-pick #100 to release-4.0", "type/cherry-pick-for-release-4.0")},
+			originals: []*github.PullRequest{original},
+			numbers:   []int{200},
+			body:      "### Release note\n- fix a panic",
+			labels:    []string{"type/cherry-pick-for-release-4.0", "type/bug-fix"},
+			fetched:   1,
+		},
+		{
+			name:    "drop collected original",
+			pulls:   []*github.PullRequest{original, testPull(200, nil, "executor: fix panic (#100)", "### Release note\n- fix a panic", "type/bug-fix")},
+			numbers: []int{200},
+			body:    "### Release note\n- fix a panic",
+			labels:  []string{"type/bug-fix"},
+			fetched: 0,
+		},
+		{
+			name:      "keep own note and labels",
+			pulls:     []*github.PullRequest{testPull(200, nil, "planner: support syntax (#101)", "### Release note\n- support syntax", "type/new-feature")},
+			originals: []*github.PullRequest{onMaster(testPull(101, nil, "planner: support syntax", "### Release note\n- original", "type/bug-fix"))},
+			numbers:   []int{200},
+			body:      "### Release note\n- support syntax",
+			labels:    []string{"type/new-feature"},
+			fetched:   0,
+		},
+		{
+			// issue number in title is not an original
+			name:    "title ends with issue number",
+			pulls:   []*github.PullRequest{testPull(200, nil, "executor: fix panic (#77)", "", "type/bug-fix")},
+			numbers: []int{200},
+			body:    "",
+			labels:  []string{"type/bug-fix"},
+			fetched: 1,
+		},
+		{
+			// the cited number happens to be another pull in the milestone
+			name: "title cites unrelated pull",
+			pulls: []*github.PullRequest{
+				testPull(101, nil, "planner: support syntax", "### Release note\n- support syntax", "type/new-feature"),
+				testPull(200, nil, "executor: fix panic (#101)", "### Release note\n- fix a panic", "type/bug-fix"),
+			},
+			numbers: []int{101, 200},
+			body:    "### Release note\n- support syntax",
+			labels:  []string{"type/new-feature"},
+			fetched: 0,
+		},
+		{
+			// an original on the same branch is not cherry-picked
+			name: "title cites pull on same branch",
+			pulls: []*github.PullRequest{
+				testPull(101, nil, "executor: fix panic", "### Release note\n- fix a panic", "type/bug-fix"),
+				testPull(200, nil, "executor: fix panic (#101)", "", "type/cherry-pick-for-release-4.0"),
+			},
+			numbers: []int{101, 200},
+			body:    "### Release note\n- fix a panic",
+			labels:  []string{"type/bug-fix"},
+			fetched: 0,
+		},
+	} {
+		server := githubtest.NewServer()
+		server.AddRepo(testTiDB)
+		for _, pull := range c.originals {
+			server.AddPull(testTiDB, pull)
+		}
+		collector := newTestCollector(t, server, &config.Config{})
+		collector.HasNote = func(repo types.Repo, pull *github.PullRequest) bool {
+			return strings.Contains(pull.GetBody(), "Release note")
+		}
+
+		pulls, err := collector.ResolveCherryPicks(context.Background(), testTiDB, c.pulls)
+		require.Nil(t, err, c.name)
+		var numbers []int
+		for _, pull := range pulls {
+			numbers = append(numbers, pull.GetNumber())
+		}
+		assert.Equal(t, numbers, c.numbers, c.name)
+		assert.Equal(t, pulls[0].GetBody(), c.body, c.name)
+		var labels []string
+		for _, label := range pulls[0].Labels {
+			labels = append(labels, label.GetName())
+		}
+		assert.Equal(t, labels, c.labels, c.name)
+		assert.Equal(t, server.CountRequests("GET /repos/pingcap/tidb/pulls/"), c.fetched, c.name)
+		server.Close()
+	}
+}
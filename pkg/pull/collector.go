@@ -1,110 +1,101 @@
 package pull
 
 import (
-	"fmt"
-	"strings"
+	"context"
 
 	"github.com/google/go-github/v30/github"
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
 	"github.com/you06/releaser/config"
+	"github.com/you06/releaser/pkg/forge"
 	"github.com/you06/releaser/pkg/types"
 	"github.com/you06/releaser/pkg/utils"
 )
 
 // Collector for collect pulls
 type Collector struct {
-	github *github.Client
+	forges *forge.Registry
 	Config *config.Config
+	// HasNote checks if pull has release note, cherry-picks inherit notes of their originals if not,
+	// all pulls are treated as having notes if it's nil
+	HasNote func(repo types.Repo, pull *github.PullRequest) bool
+
+	milestones milestoneCache
 }
 
 // New creates Collector instance
-func New(g *github.Client, config *config.Config) *Collector {
-	return &Collector{g, config}
+func New(forges *forge.Registry, config *config.Config) *Collector {
+	return &Collector{forges: forges, Config: config}
 }
 
 // ListPRList lists PR list in a version
-func (c *Collector) ListPRList(repo types.Repo, version string) ([]*github.PullRequest, error) {
-	milestone, err := c.GetVersionMilestone(repo, version)
+func (c *Collector) ListPRList(ctx context.Context, repo types.Repo, version string) ([]*github.PullRequest, error) {
+	milestone, err := c.GetVersionMilestone(ctx, repo, version)
 	if err != nil {
 		return []*github.PullRequest{}, errors.Trace(err)
 	}
-	_, pulls, err := c.ListAllMilestoneContents(repo, milestone)
+	_, pulls, err := c.ListAllMilestoneContents(ctx, repo, milestone)
 	if err != nil {
 		return []*github.PullRequest{}, errors.Trace(err)
 	}
 	return pulls, nil
 }
 
-// GetVersionMilestone gets milestone
-func (c *Collector) GetVersionMilestone(repo types.Repo, version string) (*github.Milestone, error) {
-	milestones, err := c.ListAllMilestones(repo)
+// GetVersionMilestone gets the milestone whose title matches a title pattern of repo by version exactly,
+// open milestones are preferred if several match
+func (c *Collector) GetVersionMilestone(ctx context.Context, repo types.Repo, version string) (*github.Milestone, error) {
+	milestones, err := c.ListAllMilestones(ctx, repo)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	for _, milestone := range milestones {
-		if strings.Contains(strings.ToLower(milestone.GetTitle()), strings.ToLower(version)) {
-			return milestone, nil
-		}
+	matched, err := matchMilestones(milestones, c.milestoneTitles(repo), version)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	matched = preferOpen(matched)
+	switch len(matched) {
+	case 0:
+		return nil, errors.Errorf("milestone not found for %s in %s", version, repo)
+	case 1:
+		return matched[0], nil
+	default:
+		return nil, errors.Errorf("milestone of %s is ambiguous in %s: %s", version, repo, milestoneTitleList(matched))
 	}
-	return nil, errors.New("milestone not found")
 }
 
-// ListAllMilestones lists milestones list in a version
-func (c *Collector) ListAllMilestones(repo types.Repo) ([]*github.Milestone, error) {
-	var (
-		page    = 0
-		perpage = 100
-		all     []*github.Milestone
-		batch   []*github.Milestone
-		err     error
-	)
-	for page == 0 || len(batch) == perpage {
-		page++
-		ctx, _ := utils.NewTimeoutContext()
-		batch, _, err = c.github.Issues.ListMilestones(ctx, repo.Owner, repo.Repo, &github.MilestoneListOptions{
-			State: "all",
-			ListOptions: github.ListOptions{
-				Page:    page,
-				PerPage: perpage,
-			},
-		})
+// ListAllMilestones lists milestones in all states, the list of a repo is fetched once it succeeds
+func (c *Collector) ListAllMilestones(ctx context.Context, repo types.Repo) ([]*github.Milestone, error) {
+	list := c.milestones.get(repo)
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	if !list.listed {
+		// pages are listed with their own timeouts
+		milestones, err := c.forges.For(repo).ListMilestones(ctx, repo, "all")
 		if err != nil {
 			return []*github.Milestone{}, errors.Trace(err)
 		}
-		all = append(all, batch...)
+		list.milestones, list.listed = milestones, true
 	}
-	return all, nil
+	return list.milestones, nil
 }
 
 // ListAllOpenedMilestones lists milestones in opened state
-func (c *Collector) ListAllOpenedMilestones(repo types.Repo) ([]*github.Milestone, error) {
-	var (
-		page    = 0
-		perpage = 100
-		all     []*github.Milestone
-		batch   []*github.Milestone
-		err     error
-	)
-	for page == 0 || len(batch) == perpage {
-		page++
-		ctx, _ := utils.NewTimeoutContext()
-		batch, _, err = c.github.Issues.ListMilestones(ctx, repo.Owner, repo.Repo, &github.MilestoneListOptions{
-			State: "all",
-			ListOptions: github.ListOptions{
-				Page:    page,
-				PerPage: perpage,
-			},
-		})
-		if err != nil {
-			return []*github.Milestone{}, errors.Trace(err)
+func (c *Collector) ListAllOpenedMilestones(ctx context.Context, repo types.Repo) ([]*github.Milestone, error) {
+	all, err := c.ListAllMilestones(ctx, repo)
+	if err != nil {
+		return []*github.Milestone{}, errors.Trace(err)
+	}
+	var opened []*github.Milestone
+	for _, milestone := range all {
+		if milestone.GetState() == "open" {
+			opened = append(opened, milestone)
 		}
-		all = append(all, batch...)
 	}
-	return all, nil
+	return opened, nil
 }
 
 // ListAllMilestoneContents lists issues and pull requests in a milestone
-func (c *Collector) ListAllMilestoneContents(repo types.Repo, milestone *github.Milestone) ([]*github.Issue, []*github.PullRequest, error) {
+func (c *Collector) ListAllMilestoneContents(ctx context.Context, repo types.Repo, milestone *github.Milestone) ([]*github.Issue, []*github.PullRequest, error) {
 	var (
 		issues []*github.Issue
 		pulls  []*github.PullRequest
@@ -113,53 +104,59 @@ func (c *Collector) ListAllMilestoneContents(repo types.Repo, milestone *github.
 		return issues, pulls, errors.New("milestone with id 0")
 	}
 
-	all, err := c.ListAllIssuesFrom(repo, milestone)
+	all, err := c.ListAllIssuesFrom(ctx, repo, milestone)
 	if err != nil {
 		return issues, pulls, errors.Trace(err)
 	}
 
+	var pullItems []*github.Issue
 	for _, item := range all {
 		if item.IsPullRequest() {
-			ctx, _ := utils.NewTimeoutContext()
-			pull, _, err := c.github.PullRequests.Get(ctx, repo.Owner, repo.Repo, item.GetNumber())
-			if err != nil {
-				return issues, pulls, errors.Trace(err)
-			}
-			pulls = append(pulls, pull)
+			pullItems = append(pullItems, item)
 		} else {
 			issues = append(issues, item)
 		}
 	}
 
-	return issues, pulls, err
-}
-
-// ListAllIssuesFrom lists issues from
-func (c *Collector) ListAllIssuesFrom(repo types.Repo, milestone *github.Milestone) ([]*github.Issue, error) {
-	var (
-		page    = 0
-		perpage = 100
-		all     []*github.Issue
-		batch   []*github.Issue
-	)
+	if c.Config.GraphQL {
+		if lister, ok := c.forges.For(repo).(forge.MilestonePullsLister); ok {
+			// pages are listed with their own timeouts
+			pulls, err = lister.ListMilestonePulls(ctx, repo, milestone)
+			if err == nil {
+				pulls, err = c.ResolveCherryPicks(ctx, repo, pulls)
+				return issues, pulls, errors.Trace(err)
+			}
+			log.Warnf("list pulls of %s milestone %s in bulk failed, fallback to REST API, %v",
+				repo, milestone.GetTitle(), err)
+			pulls = nil
+		}
+	}
 
-	for page == 0 || len(batch) == perpage {
-		page++
-		ctx, _ := utils.NewTimeoutContext()
-		batch, _, err := c.github.Issues.ListByRepo(ctx, repo.Owner, repo.Repo, &github.IssueListByRepoOptions{
-			Milestone: fmt.Sprintf("%d", milestone.GetNumber()),
-			State:     "all",
-			ListOptions: github.ListOptions{
-				Page:    page,
-				PerPage: perpage,
-			},
-		})
+	for _, item := range pullItems {
+		pull, err := c.getPull(ctx, repo, item.GetNumber())
 		if err != nil {
-			return all, errors.Trace(err)
+			return issues, pulls, errors.Trace(err)
 		}
-		all = append(all, batch...)
+		pulls = append(pulls, pull)
 	}
 
+	pulls, err = c.ResolveCherryPicks(ctx, repo, pulls)
+	return issues, pulls, errors.Trace(err)
+}
+
+func (c *Collector) getPull(ctx context.Context, repo types.Repo, number int) (*github.PullRequest, error) {
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
+	pull, err := c.forges.For(repo).GetPull(ctx, repo, number)
+	return pull, errors.Trace(err)
+}
+
+// ListAllIssuesFrom lists issues from
+func (c *Collector) ListAllIssuesFrom(ctx context.Context, repo types.Repo, milestone *github.Milestone) ([]*github.Issue, error) {
+	all, err := c.forges.For(repo).ListMilestoneIssues(ctx, repo, milestone)
+	if err != nil {
+		return all, errors.Trace(err)
+	}
 	return all, nil
 }
 
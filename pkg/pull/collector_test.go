@@ -0,0 +1,66 @@
+package pull
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you06/releaser/config"
+	"github.com/you06/releaser/pkg/forge"
+	"github.com/you06/releaser/pkg/githubtest"
+	"github.com/you06/releaser/pkg/types"
+)
+
+var testTiDB = types.Repo{Owner: "pingcap", Repo: "tidb"}
+
+// newTestCollector creates Collector against a fake GitHub server
+func newTestCollector(t *testing.T, server *githubtest.Server, cfg *config.Config) *Collector {
+	g, err := forge.NewGitHub(server.APIURL(), "", "", "")
+	require.Nil(t, err)
+	return New(forge.NewSingle(g), cfg)
+}
+
+func testPull(number int, milestone *github.Milestone, title, body string, labels ...string) *github.PullRequest {
+	pull := github.PullRequest{
+		Number:    github.Int(number),
+		Title:     github.String(title),
+		Body:      github.String(body),
+		Merged:    github.Bool(true),
+		Milestone: milestone,
+		Base:      &github.PullRequestBranch{Ref: github.String("release-4.0")},
+	}
+	for _, label := range labels {
+		pull.Labels = append(pull.Labels, &github.Label{Name: github.String(label)})
+	}
+	return &pull
+}
+
+func TestListAllMilestoneContents(t *testing.T) {
+	for _, c := range []struct {
+		name           string
+		graphql        bool
+		disableGraphQL bool
+		restPulls      int
+	}{
+		{"graphql", true, false, 0},
+		{"graphql unavailable", true, true, 2},
+		{"graphql disabled", false, false, 2},
+	} {
+		server := githubtest.NewServer()
+		server.AddRepo(testTiDB)
+		server.DisableGraphQL = c.disableGraphQL
+		milestone := server.AddMilestone(testTiDB, "v4.0.6", "open")
+		server.AddPull(testTiDB, testPull(100, milestone, "executor: fix panic", ""))
+		server.AddPull(testTiDB, testPull(101, milestone, "planner: fix bug", ""))
+
+		collector := newTestCollector(t, server, &config.Config{GraphQL: c.graphql})
+		_, pulls, err := collector.ListAllMilestoneContents(context.Background(), testTiDB, milestone)
+		require.Nil(t, err, c.name)
+		assert.Equal(t, len(pulls), 2, c.name)
+		assert.Equal(t, pulls[0].GetBase().GetRef(), "release-4.0", c.name)
+		assert.Equal(t, server.CountRequests("GET /repos/pingcap/tidb/pulls/"), c.restPulls, c.name)
+		server.Close()
+	}
+}
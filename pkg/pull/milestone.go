@@ -0,0 +1,138 @@
+package pull
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/you06/releaser/pkg/types"
+)
+
+var (
+	// DefaultMilestoneTitles are title patterns of milestones of repos which are not configured
+	DefaultMilestoneTitles = []string{"{version}"}
+)
+
+// versionGroup captures the version in a title when the version is unknown
+const versionGroup = `(v?\d[0-9A-Za-z.+-]*)`
+
+// milestoneList is the memoized milestone list of a repo
+type milestoneList struct {
+	mu         sync.Mutex
+	listed     bool
+	milestones []*github.Milestone
+}
+
+// milestoneCache memoizes milestones of repos during a run
+type milestoneCache struct {
+	mu    sync.Mutex
+	repos map[types.Repo]*milestoneList
+}
+
+func (c *milestoneCache) get(repo types.Repo) *milestoneList {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.repos == nil {
+		c.repos = make(map[types.Repo]*milestoneList)
+	}
+	list, ok := c.repos[repo]
+	if !ok {
+		list = &milestoneList{}
+		c.repos[repo] = list
+	}
+	return list
+}
+
+// milestoneTitles gets title patterns of milestones of repo, the config without repos is the default
+func (c *Collector) milestoneTitles(repo types.Repo) []string {
+	titles := DefaultMilestoneTitles
+	for _, milestone := range c.Config.Milestones {
+		if len(milestone.Repos) == 0 {
+			titles = milestone.Titles
+			continue
+		}
+		for _, r := range milestone.Repos {
+			if parsed, err := types.ParseRepo(r); err == nil && parsed == repo {
+				return milestone.Titles
+			}
+		}
+	}
+	return titles
+}
+
+// titlePattern compiles a title pattern to a case insensitive regular expression of the whole title,
+// {version} matches version with an optional leading "v", or any version if version is empty
+func titlePattern(title, version string) (*regexp.Regexp, error) {
+	group := versionGroup
+	if version != "" {
+		group = "(v?" + regexp.QuoteMeta(strings.TrimPrefix(strings.ToLower(version), "v")) + ")"
+	}
+	var parts []string
+	for _, part := range strings.Split(title, "{version}") {
+		parts = append(parts, regexp.QuoteMeta(part))
+	}
+	return regexp.Compile("(?i)^" + strings.Join(parts, group) + "$")
+}
+
+// MilestoneVersion gets the version in milestone title of repo by title patterns, title itself if no pattern matches
+func (c *Collector) MilestoneVersion(repo types.Repo, title string) string {
+	for _, pattern := range c.milestoneTitles(repo) {
+		re, err := titlePattern(pattern, "")
+		if err != nil {
+			continue
+		}
+		if match := re.FindStringSubmatch(title); len(match) > 1 {
+			return match[1]
+		}
+	}
+	return title
+}
+
+// matchMilestones gets milestones whose titles match any title pattern by version exactly
+func matchMilestones(milestones []*github.Milestone, titles []string, version string) ([]*github.Milestone, error) {
+	var res []*github.Milestone
+	for _, title := range titles {
+		re, err := titlePattern(title, version)
+		if err != nil {
+			return nil, err
+		}
+		for _, milestone := range milestones {
+			if re.MatchString(milestone.GetTitle()) && !containsMilestone(res, milestone) {
+				res = append(res, milestone)
+			}
+		}
+	}
+	return res, nil
+}
+
+// preferOpen keeps open milestones if there is any
+func preferOpen(milestones []*github.Milestone) []*github.Milestone {
+	var open []*github.Milestone
+	for _, milestone := range milestones {
+		if milestone.GetState() == "open" {
+			open = append(open, milestone)
+		}
+	}
+	if len(open) == 0 {
+		return milestones
+	}
+	return open
+}
+
+func containsMilestone(milestones []*github.Milestone, m *github.Milestone) bool {
+	for _, milestone := range milestones {
+		if milestone.GetNumber() == m.GetNumber() {
+			return true
+		}
+	}
+	return false
+}
+
+func milestoneTitleList(milestones []*github.Milestone) string {
+	titles := make([]string, 0, len(milestones))
+	for _, milestone := range milestones {
+		titles = append(titles, milestone.GetTitle())
+	}
+	return strings.Join(titles, ", ")
+}
@@ -0,0 +1,87 @@
+package pull
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/you06/releaser/config"
+	"github.com/you06/releaser/pkg/githubtest"
+	"github.com/you06/releaser/pkg/types"
+)
+
+func TestGetVersionMilestone(t *testing.T) {
+	server := githubtest.NewServer()
+	defer server.Close()
+	tidb := types.Repo{Owner: "pingcap", Repo: "tidb"}
+	pd := types.Repo{Owner: "pingcap", Repo: "pd"}
+	server.AddRepo(tidb)
+	server.AddRepo(pd)
+	server.AddMilestone(tidb, "v4.0.10", "open")
+	server.AddMilestone(tidb, "v4.0.1", "closed")
+	server.AddMilestone(tidb, "v5.0.0", "closed")
+	server.AddMilestone(tidb, "5.0.0", "closed")
+	server.AddMilestone(tidb, "v5.1.0", "closed")
+	server.AddMilestone(tidb, "5.1.0", "open")
+	server.AddMilestone(pd, "PD v4.0.1", "open")
+	server.AddMilestone(pd, "v4.0.1-tools", "open")
+
+	c := newTestCollector(t, server, &config.Config{
+		Milestones: []config.Milestone{{Repos: []string{"pingcap/pd"}, Titles: []string{"{version}", "PD {version}"}}},
+	})
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		repo           types.Repo
+		version, title string
+	}{
+		{tidb, "v4.0.1", "v4.0.1"},
+		{tidb, "V4.0.10", "v4.0.10"},
+		{tidb, "v5.1.0", "5.1.0"},
+		{pd, "v4.0.1", "PD v4.0.1"},
+	} {
+		milestone, err := c.GetVersionMilestone(ctx, tc.repo, tc.version)
+		require.Nil(t, err, tc.version)
+		assert.Equal(t, milestone.GetTitle(), tc.title, tc.version)
+	}
+
+	_, err := c.GetVersionMilestone(ctx, tidb, "v4.0")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "milestone not found")
+	_, err = c.GetVersionMilestone(ctx, tidb, "v5.0.0")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ambiguous")
+	assert.Contains(t, err.Error(), "v5.0.0, 5.0.0")
+
+	opened, err := c.ListAllOpenedMilestones(ctx, tidb)
+	require.Nil(t, err)
+	assert.Equal(t, len(opened), 2)
+
+	// milestones of a repo are listed once in a run
+	assert.Equal(t, server.CountRequests("GET /repos/pingcap/tidb/milestones"), 1)
+
+	// failures are not memoized
+	tikv := types.Repo{Owner: "tikv", Repo: "tikv"}
+	_, err = c.GetVersionMilestone(ctx, tikv, "v4.0.1")
+	require.NotNil(t, err)
+	assert.NotContains(t, err.Error(), "milestone not found")
+	server.AddRepo(tikv)
+	server.AddMilestone(tikv, "v4.0.1", "open")
+	milestone, err := c.GetVersionMilestone(ctx, tikv, "v4.0.1")
+	require.Nil(t, err)
+	assert.Equal(t, milestone.GetTitle(), "v4.0.1")
+	_, err = c.ListAllMilestones(ctx, tikv)
+	require.Nil(t, err)
+	assert.Equal(t, server.CountRequests("GET /repos/tikv/tikv/milestones"), 2)
+}
+
+func TestMilestoneVersion(t *testing.T) {
+	c := New(nil, &config.Config{
+		Milestones: []config.Milestone{{Titles: []string{"TiDB {version}", "{version}"}}},
+	})
+	tidb := types.Repo{Owner: "pingcap", Repo: "tidb"}
+	assert.Equal(t, c.MilestoneVersion(tidb, "TiDB v4.0.6"), "v4.0.6")
+	assert.Equal(t, c.MilestoneVersion(tidb, "v5.0.0-rc"), "v5.0.0-rc")
+	assert.Equal(t, c.MilestoneVersion(tidb, "next"), "next")
+}
@@ -0,0 +1,118 @@
+package pull
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/you06/releaser/pkg/forge"
+	"github.com/you06/releaser/pkg/types"
+	"github.com/you06/releaser/pkg/utils"
+)
+
+var (
+	// mergePattern matches subjects of merge commits made by GitHub
+	mergePattern = regexp.MustCompile(`^Merge pull request #(\d+)`)
+	// squashPattern matches subjects of squashed commits, like "executor: fix panic (#1234)"
+	squashPattern = regexp.MustCompile(`\(#(\d+)\)\s*$`)
+)
+
+// CompareCommits lists commits between from and to by API,
+// complete is false if forge can not compare or the commits are truncated
+func (c *Collector) CompareCommits(ctx context.Context, repo types.Repo, from, to string) ([]*github.RepositoryCommit, bool, error) {
+	comparer, ok := c.forges.For(repo).(forge.CommitComparer)
+	if !ok {
+		return nil, false, nil
+	}
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
+	comparison, err := comparer.CompareCommits(ctx, repo, from, to)
+	if err != nil {
+		return nil, false, errors.Trace(err)
+	}
+	return comparison.Commits, comparison.GetTotalCommits() <= len(comparison.Commits), nil
+}
+
+// ListCommitPulls lists merged pulls which introduced commits in order of commits.
+// Pulls are found by subjects of merge commits and squashed commits first, then by API.
+func (c *Collector) ListCommitPulls(ctx context.Context, repo types.Repo, commits []*github.RepositoryCommit) ([]*github.PullRequest, error) {
+	var (
+		numbers []int
+		seen    = make(map[int]bool)
+		add     = func(number int) {
+			if !seen[number] {
+				seen[number] = true
+				numbers = append(numbers, number)
+			}
+		}
+	)
+	for _, commit := range commits {
+		if number, ok := commitPullNumber(commit.GetCommit().GetMessage()); ok {
+			add(number)
+			continue
+		}
+		number, err := c.findCommitPull(ctx, repo, commit.GetSHA())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if number == 0 {
+			log.Warnf("no pull found for commit %s in %s", commit.GetSHA(), repo)
+			continue
+		}
+		add(number)
+	}
+
+	pulls := make([]*github.PullRequest, len(numbers))
+	err := utils.Parallel(ctx, c.Config.Concurrency, len(numbers), func(ctx context.Context, i int) error {
+		pull, err := c.getPull(ctx, repo, numbers[i])
+		pulls[i] = pull
+		return errors.Trace(err)
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var merged []*github.PullRequest
+	for _, pull := range pulls {
+		if pull.GetMerged() {
+			merged = append(merged, pull)
+		}
+	}
+	merged, err = c.ResolveCherryPicks(ctx, repo, merged)
+	return merged, errors.Trace(err)
+}
+
+// findCommitPull finds the merged pull of a commit by API, 0 if there is not
+func (c *Collector) findCommitPull(ctx context.Context, repo types.Repo, sha string) (int, error) {
+	lister, ok := c.forges.For(repo).(forge.CommitPullsLister)
+	if !ok {
+		return 0, nil
+	}
+	ctx, cancel := utils.NewTimeoutContext(ctx)
+	defer cancel()
+	pulls, err := lister.ListCommitPulls(ctx, repo, sha)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	for _, pull := range pulls {
+		if pull.MergedAt != nil {
+			return pull.GetNumber(), nil
+		}
+	}
+	return 0, nil
+}
+
+// commitPullNumber gets pull number from the subject of a commit message
+func commitPullNumber(message string) (int, bool) {
+	subject := strings.TrimSpace(strings.SplitN(message, "\n", 2)[0])
+	for _, pattern := range []*regexp.Regexp{mergePattern, squashPattern} {
+		if match := pattern.FindStringSubmatch(subject); len(match) == 2 {
+			number, err := strconv.Atoi(match[1])
+			return number, err == nil
+		}
+	}
+	return 0, false
+}
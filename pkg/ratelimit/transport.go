@@ -0,0 +1,296 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/you06/releaser/pkg/utils"
+)
+
+const (
+	headerRemaining  = "X-RateLimit-Remaining"
+	headerReset      = "X-RateLimit-Reset"
+	headerRetryAfter = "Retry-After"
+
+	defaultMaxRetries = 5
+	defaultMaxWait    = time.Hour
+	defaultBackoff    = time.Second
+	// secondaryWait is used when a secondary rate limit response has no Retry-After header
+	secondaryWait = time.Minute
+	// resetSkew is added to reset time against clock difference with GitHub
+	resetSkew = time.Second
+)
+
+// Transport is a http.RoundTripper which waits for GitHub rate limits and
+// retries requests failed by rate limits or transient server errors
+type Transport struct {
+	// Base is the underlying transport, http.DefaultTransport is used if nil
+	Base http.RoundTripper
+	// MaxRetries is the max retry times of a request
+	MaxRetries int
+	// MaxWait is the longest time to wait for a single retry,
+	// the response is returned to caller if GitHub asks for waiting longer
+	MaxWait time.Duration
+	// Backoff is the initial wait of retrying transient errors, doubled every retry
+	Backoff time.Duration
+
+	mu sync.Mutex
+	// blockedUntil is the reset time after quota is exhausted,
+	// all requests wait for it before being sent
+	blockedUntil time.Time
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewTransport creates Transport instance with default retry options
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{
+		Base:       base,
+		MaxRetries: defaultMaxRetries,
+		MaxWait:    defaultMaxWait,
+		Backoff:    defaultBackoff,
+		now:        time.Now,
+		sleep:      sleep,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// waiting for rate limits should not be counted into the timeout of a single API call,
+	// so the wait is done by the parent context and every attempt gets a new timeout
+	var (
+		parent  = utils.WithoutTimeout(req.Context())
+		attempt = req
+		cancel  = func() {}
+		backoff = t.Backoff
+	)
+	for retry := 0; ; retry++ {
+		waited, err := t.waitBlocked(parent, req)
+		if err != nil {
+			cancel()
+			return nil, errors.Trace(err)
+		}
+		if waited && retry == 0 {
+			// the timeout of original request may be used up by waiting
+			if attempt, cancel, err = t.newAttempt(parent, req); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+
+		resp, err := t.base().RoundTrip(attempt)
+		if err != nil {
+			cancel()
+			if retry >= t.MaxRetries || !idempotent(req) || parent.Err() != nil {
+				return nil, err
+			}
+			log.Warnf("%s %s failed, retry %d/%d in %s, %v", req.Method, req.URL, retry+1, t.MaxRetries, backoff, err)
+			if err := t.sleep(parent, backoff); err != nil {
+				return nil, errors.Trace(err)
+			}
+			backoff *= 2
+		} else {
+			t.observe(resp)
+			wait, reason, retryable := t.retryAfter(req, resp, backoff)
+			if !retryable || retry >= t.MaxRetries || wait > t.MaxWait {
+				if retryable && wait > t.MaxWait {
+					log.Warnf("%s %s hit %s, wait %s exceeds max wait %s, give up",
+						req.Method, req.URL, reason, wait, t.MaxWait)
+				}
+				// cancel the attempt context after the response body is consumed
+				resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+				return resp, nil
+			}
+			drain(resp)
+			cancel()
+			log.Warnf("%s %s hit %s, retry %d/%d in %s",
+				req.Method, req.URL, reason, retry+1, t.MaxRetries, wait.Round(time.Second))
+			if err := t.sleep(parent, wait); err != nil {
+				return nil, errors.Trace(err)
+			}
+			if reason == reasonServerError {
+				backoff *= 2
+			}
+		}
+
+		if attempt, cancel, err = t.newAttempt(parent, req); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+}
+
+const (
+	reasonRateLimit   = "rate limit"
+	reasonSecondary   = "secondary rate limit"
+	reasonServerError = "server error"
+)
+
+// retryAfter tells how long to wait before retrying the response
+func (t *Transport) retryAfter(req *http.Request, resp *http.Response, backoff time.Duration) (time.Duration, string, bool) {
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		// the request is rejected before being processed, so any method is safe to retry
+		if resp.Header.Get(headerRemaining) == "0" {
+			if reset, ok := t.resetTime(resp); ok {
+				return t.until(reset), reasonRateLimit, true
+			}
+		}
+		if wait, ok := parseRetryAfter(resp); ok {
+			return wait, reasonSecondary, true
+		}
+		if isSecondaryLimit(resp) {
+			return secondaryWait, reasonSecondary, true
+		}
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if !idempotent(req) {
+			return 0, "", false
+		}
+		if wait, ok := parseRetryAfter(resp); ok {
+			return wait, reasonServerError, true
+		}
+		return backoff, reasonServerError, true
+	}
+	return 0, "", false
+}
+
+// observe records the reset time when quota is exhausted
+func (t *Transport) observe(resp *http.Response) {
+	if resp.Header.Get(headerRemaining) != "0" {
+		return
+	}
+	reset, ok := t.resetTime(resp)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if reset.After(t.blockedUntil) {
+		t.blockedUntil = reset
+	}
+}
+
+// waitBlocked waits until quota is reset, returns whether it has waited
+func (t *Transport) waitBlocked(ctx context.Context, req *http.Request) (bool, error) {
+	t.mu.Lock()
+	wait := t.until(t.blockedUntil)
+	t.mu.Unlock()
+	if wait <= 0 {
+		return false, nil
+	}
+	if wait > t.MaxWait {
+		return false, errors.Errorf("rate limit exceeded, reset in %s exceeds max wait %s", wait, t.MaxWait)
+	}
+	log.Warnf("rate limit exhausted, %s %s waits %s for reset", req.Method, req.URL, wait.Round(time.Second))
+	return true, errors.Trace(t.sleep(ctx, wait))
+}
+
+// newAttempt clones the request with a new timeout and a rewound body
+func (t *Transport) newAttempt(parent context.Context, req *http.Request) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := utils.NewTimeoutContext(parent)
+	attempt := req.Clone(ctx)
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			cancel()
+			return nil, nil, errors.Errorf("%s %s cannot be retried, body is not rewindable", req.Method, req.URL)
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, nil, errors.Trace(err)
+		}
+		attempt.Body = body
+	}
+	return attempt, cancel, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+func (t *Transport) resetTime(resp *http.Response) (time.Time, bool) {
+	reset, err := strconv.ParseInt(resp.Header.Get(headerReset), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(reset, 0).Add(resetSkew), true
+}
+
+func (t *Transport) until(tm time.Time) time.Duration {
+	wait := tm.Sub(t.now())
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(resp.Header.Get(headerRetryAfter))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// isSecondaryLimit checks the message of 403 response, body is kept for caller
+func isSecondaryLimit(resp *http.Response) bool {
+	if resp.Body == nil {
+		return false
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	message := strings.ToLower(string(body))
+	return strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse detection")
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func drain(resp *http.Response) {
+	if resp.Body != nil {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
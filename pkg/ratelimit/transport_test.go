@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stub replies queued responses in order and records request bodies
+type stub struct {
+	mu        sync.Mutex
+	responses []func(w http.ResponseWriter)
+	bodies    []string
+}
+
+func (s *stub) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	s.bodies = append(s.bodies, string(body))
+	if len(s.responses) == 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	reply := s.responses[0]
+	s.responses = s.responses[1:]
+	reply(w)
+}
+
+func reply(code int, headers map[string]string, body string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for k, v := range headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(code)
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func newTestTransport(now time.Time) (*Transport, *[]time.Duration) {
+	var sleeps []time.Duration
+	t := NewTransport(nil)
+	// the clock moves forward on sleeping only
+	t.now = func() time.Time { return now }
+	t.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+		return nil
+	}
+	return t, &sleeps
+}
+
+func do(t *testing.T, transport *Transport, method, url, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.Nil(t, err)
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	_, err = ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	return resp
+}
+
+func TestRateLimitExceeded(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	reset := strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)
+	s := stub{responses: []func(w http.ResponseWriter){
+		reply(http.StatusForbidden, map[string]string{headerRemaining: "0", headerReset: reset}, `{"message":"API rate limit exceeded"}`),
+		reply(http.StatusOK, map[string]string{headerRemaining: "4999"}, "{}"),
+	}}
+	server := httptest.NewServer(http.HandlerFunc(s.serve))
+	defer server.Close()
+
+	transport, sleeps := newTestTransport(now)
+	resp := do(t, transport, http.MethodPost, server.URL, "payload")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, *sleeps, []time.Duration{30*time.Second + resetSkew})
+	assert.Equal(t, s.bodies, []string{"payload", "payload"}, "body is replayed")
+}
+
+func TestSecondaryRateLimit(t *testing.T) {
+	s := stub{responses: []func(w http.ResponseWriter){
+		reply(http.StatusForbidden, map[string]string{headerRetryAfter: "5"}, `{"message":"secondary rate limit"}`),
+		reply(http.StatusForbidden, nil, `{"message":"You have exceeded a secondary rate limit"}`),
+	}}
+	server := httptest.NewServer(http.HandlerFunc(s.serve))
+	defer server.Close()
+
+	transport, sleeps := newTestTransport(time.Now())
+	resp := do(t, transport, http.MethodGet, server.URL, "")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, *sleeps, []time.Duration{5 * time.Second, secondaryWait})
+}
+
+func TestServerErrorBackoff(t *testing.T) {
+	s := stub{responses: []func(w http.ResponseWriter){
+		reply(http.StatusBadGateway, nil, ""),
+		reply(http.StatusBadGateway, nil, ""),
+		reply(http.StatusBadGateway, nil, ""),
+	}}
+	server := httptest.NewServer(http.HandlerFunc(s.serve))
+	defer server.Close()
+
+	transport, sleeps := newTestTransport(time.Now())
+	resp := do(t, transport, http.MethodGet, server.URL, "")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, *sleeps, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second})
+
+	// not idempotent
+	s.responses = []func(w http.ResponseWriter){reply(http.StatusBadGateway, nil, "")}
+	resp = do(t, transport, http.MethodPost, server.URL, "")
+	assert.Equal(t, resp.StatusCode, http.StatusBadGateway)
+	assert.Equal(t, len(*sleeps), 3)
+
+	// give up after max retries
+	transport.MaxRetries = 1
+	s.responses = []func(w http.ResponseWriter){reply(http.StatusBadGateway, nil, ""), reply(http.StatusBadGateway, nil, "")}
+	resp = do(t, transport, http.MethodGet, server.URL, "")
+	assert.Equal(t, resp.StatusCode, http.StatusBadGateway)
+	assert.Equal(t, len(*sleeps), 4)
+}
+
+func TestWaitForReset(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	s := stub{responses: []func(w http.ResponseWriter){
+		reply(http.StatusOK, map[string]string{
+			headerRemaining: "0",
+			headerReset:     strconv.FormatInt(now.Add(time.Minute).Unix(), 10),
+		}, "{}"),
+	}}
+	server := httptest.NewServer(http.HandlerFunc(s.serve))
+	defer server.Close()
+
+	transport, sleeps := newTestTransport(now)
+	do(t, transport, http.MethodGet, server.URL, "")
+	assert.Equal(t, len(*sleeps), 0)
+	do(t, transport, http.MethodGet, server.URL, "")
+	assert.Equal(t, *sleeps, []time.Duration{time.Minute + resetSkew}, "next request waits for reset")
+
+	// reset too far away
+	s.responses = []func(w http.ResponseWriter){
+		reply(http.StatusForbidden, map[string]string{
+			headerRemaining: "0",
+			headerReset:     strconv.FormatInt(now.Add(2*time.Hour).Unix(), 10),
+		}, "{}"),
+	}
+	resp := do(t, transport, http.MethodGet, server.URL, "")
+	assert.Equal(t, resp.StatusCode, http.StatusForbidden, "rate limit error is returned")
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.Nil(t, err)
+	_, err = transport.RoundTrip(req)
+	assert.NotNil(t, err, "following request fails without waiting")
+	assert.Equal(t, len(*sleeps), 1)
+}
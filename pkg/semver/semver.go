@@ -0,0 +1,125 @@
+package semver
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// Version is a parsed semantic version
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	PreRelease []string
+	Build      string
+	Raw        string
+}
+
+// Parse parses a version string leniently,
+// leading "v" and missing minor or patch numbers are accepted
+func Parse(raw string) (Version, error) {
+	v := Version{Raw: raw}
+	s := strings.TrimPrefix(strings.TrimSpace(raw), "v")
+	if i := strings.Index(s, "+"); i >= 0 {
+		s, v.Build = s[:i], s[i+1:]
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		var pre string
+		s, pre = s[:i], s[i+1:]
+		if pre == "" {
+			return v, errors.Errorf("version %s has empty pre-release", raw)
+		}
+		v.PreRelease = strings.Split(pre, ".")
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, errors.Errorf("version %s not valid", raw)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, errors.Errorf("version %s not valid", raw)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 by semver precedence,
+// build metadata is ignored
+func (v Version) Compare(o Version) int {
+	if c := compareInt(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	// a version without pre-release has higher precedence
+	switch {
+	case len(v.PreRelease) == 0 && len(o.PreRelease) == 0:
+		return 0
+	case len(v.PreRelease) == 0:
+		return 1
+	case len(o.PreRelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.PreRelease) && i < len(o.PreRelease); i++ {
+		if c := comparePreRelease(v.PreRelease[i], o.PreRelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(v.PreRelease), len(o.PreRelease))
+}
+
+// String returns the raw version
+func (v Version) String() string {
+	return v.Raw
+}
+
+// Compare parses and compares two version strings,
+// a valid version is always greater than an invalid one
+// and two invalid versions are compared as strings
+func Compare(a, b string) int {
+	va, errA := Parse(a)
+	vb, errB := Parse(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	return va.Compare(vb)
+}
+
+func comparePreRelease(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInt(na, nb)
+	// numeric identifiers have lower precedence than alphanumeric ones
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse("v4.0.0-beta.2+build.1")
+	assert.Nil(t, err)
+	assert.Equal(t, v.Major, 4)
+	assert.Equal(t, v.Minor, 0)
+	assert.Equal(t, v.PreRelease, []string{"beta", "2"})
+	assert.Equal(t, v.Build, "build.1")
+
+	v, err = Parse("0.2")
+	assert.Nil(t, err)
+	assert.Equal(t, v.Minor, 2)
+
+	_, err = Parse("master")
+	assert.NotNil(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	assert.Equal(t, Compare("v1.2.0", "v1.10.0"), -1)
+	assert.Equal(t, Compare("v1.0.0", "v1.0.0-rc.1"), 1)
+	assert.Equal(t, Compare("v1.0.0-alpha", "v1.0.0-alpha.1"), -1)
+	assert.Equal(t, Compare("v1.0.0-alpha.beta", "v1.0.0-alpha.1"), 1)
+	assert.Equal(t, Compare("v0.0.0-20191106014506-c5d88d699a8d", "v0.0.0-20190516013202-4cf58ad90b6c"), 1)
+	assert.Equal(t, Compare("v1.0.0+a", "v1.0.0+b"), 0)
+	assert.Equal(t, Compare("master", "v0.1.0"), -1)
+}
@@ -9,4 +9,12 @@ const (
 	SubCmdCheckModule = "check-module"
 	// SubCmdGenerateReleaseNote is the command which generate release notes via pull requests
 	SubCmdGenerateReleaseNote = "generate-release-note"
+	// SubCmdLintNotes is the command which checks release notes of open pulls in milestone
+	SubCmdLintNotes = "lint-notes"
+	// SubCmdCheckMilestone is the command which checks pulls in milestone against release branch history
+	SubCmdCheckMilestone = "check-milestone"
+	// SubCmdCache is the command which manages API response cache
+	SubCmdCache = "cache"
+	// SubCmdCacheClear is the subcommand of cache which removes all cached responses
+	SubCmdCacheClear = "clear"
 )
@@ -1,5 +1,11 @@
 package types
 
+import (
+	"text/template"
+
+	"github.com/you06/releaser/pkg/classify"
+)
+
 type ProductItem struct {
 	Title    string
 	Repo     Repo
@@ -8,9 +14,14 @@ type ProductItem struct {
 
 // Product struct
 type Product struct {
-	Name       string
-	Repos      []Repo
-	Renames    map[Repo]Repo
-	Structure  []ProductItem
-	Label2Type map[string]string
+	Name      string
+	Repos     []Repo
+	Renames   map[Repo]Repo
+	Structure []ProductItem
+	// Classifier decides types of pulls
+	Classifier *classify.Classifier
+	TypeOrder  []string
+	NoteOrder  string
+	// Template renders release note documents, default template is used if it's nil
+	Template *template.Template
 }
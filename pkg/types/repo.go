@@ -3,6 +3,8 @@ package types
 import (
 	"fmt"
 	"strings"
+
+	"github.com/juju/errors"
 )
 
 // Repo struct
@@ -14,20 +16,24 @@ type Repo struct {
 // Repos for list
 type Repos []Repo
 
-// String
-func (r Repo) String() string {
-	return fmt.Sprintf("%s/%s", r.Owner, r.Repo)
-}
+// ParseRepo parses repo from "owner/repo" format
+func ParseRepo(repo string) (Repo, error) {
+	var (
+		p = strings.Split(repo, "/")
+		r Repo
+	)
+	if len(p) != 2 {
+		return r, errors.Errorf("repo %s not valid", repo)
+	}
+
+	r.Owner, r.Repo = p[0], p[1]
 
-// ComposeHTTPS compose https git address
-func (r Repo) ComposeHTTPS() string {
-	return fmt.Sprintf("https://github.com/%s/%s.git", r.Owner, r.Repo)
+	return r, nil
 }
 
-// ComposeHTTPSWithCredential compose https git address with token
-func (r Repo) ComposeHTTPSWithCredential(user, token string) string {
-	return fmt.Sprintf("https://%s:%s@github.com/%s/%s.git",
-		user, token, r.Owner, r.Repo)
+// String
+func (r Repo) String() string {
+	return fmt.Sprintf("%s/%s", r.Owner, r.Repo)
 }
 
 // String
@@ -7,7 +7,19 @@ import (
 
 const timeout = 10 * time.Second
 
-// NewTimeoutContext create context with timeout
-func NewTimeoutContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), timeout)
+type parentKey struct{}
+
+// NewTimeoutContext create context with timeout for a single API call,
+// it's canceled along with parent
+func NewTimeoutContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithValue(parent, parentKey{}, parent), timeout)
+}
+
+// WithoutTimeout returns the parent of the innermost context created by NewTimeoutContext,
+// so that waiting like rate limit backoff is not counted into the timeout of a single API call
+func WithoutTimeout(ctx context.Context) context.Context {
+	if parent, ok := ctx.Value(parentKey{}).(context.Context); ok {
+		return parent
+	}
+	return ctx
 }
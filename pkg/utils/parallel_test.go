@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallel(t *testing.T) {
+	var (
+		running, peak int32
+		results       = make([]int, 20)
+	)
+	err := Parallel(context.Background(), 3, len(results), func(ctx context.Context, i int) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		results[i] = i * i
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.True(t, peak <= 3, "bounded")
+	for i, r := range results {
+		assert.Equal(t, r, i*i, "ordered")
+	}
+}
+
+func TestParallelCancel(t *testing.T) {
+	var started int32
+	fatal := errors.New("fatal")
+	err := Parallel(context.Background(), 2, 100, func(ctx context.Context, i int) error {
+		atomic.AddInt32(&started, 1)
+		if i == 1 {
+			return fatal
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.Equal(t, err, fatal)
+	assert.True(t, started < 100, "remaining tasks are not started")
+}